@@ -0,0 +1,225 @@
+package kiwi
+
+// This file consists of the batching of the records for the sinks that send them in bulk.
+
+/* Copyright (c) 2016-2020, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व */
+
+import (
+	"sync"
+	"time"
+)
+
+// Default settings of the batches.
+var (
+	BatchMaxRecords = 100
+	BatchMaxBytes   = 1 << 20
+	BatchInterval   = time.Second
+	BatchRetries    = 3
+	BatchBackoff    = 100 * time.Millisecond
+)
+
+// BatchOptions defines when the batch of the records flushed and how
+// the failed flushes retried. Zero values mean the defaults.
+type BatchOptions struct {
+	// MaxRecords flushes the batch when it has that number of the records.
+	MaxRecords int
+	// MaxBytes flushes the batch when its size reaches that number of bytes.
+	MaxBytes int
+	// Interval flushes the batch periodically.
+	Interval time.Duration
+	// Retries is the number of attempts to repeat failed flush.
+	// Negative value disables the retries.
+	Retries int
+	// Backoff is the initial delay before the retry. It doubled
+	// for each next attempt.
+	Backoff time.Duration
+	// OnError called when the batch could not be flushed after
+	// all the retries. The batch is dropped then.
+	OnError func(error)
+}
+
+func (o BatchOptions) withDefaults() BatchOptions {
+	if o.MaxRecords <= 0 {
+		o.MaxRecords = BatchMaxRecords
+	}
+	if o.MaxBytes <= 0 {
+		o.MaxBytes = BatchMaxBytes
+	}
+	if o.Interval <= 0 {
+		o.Interval = BatchInterval
+	}
+	if o.Retries == 0 {
+		o.Retries = BatchRetries
+	}
+	if o.Backoff <= 0 {
+		o.Backoff = BatchBackoff
+	}
+	return o
+}
+
+// retryableError marks the errors after that the flush could be
+// repeated.
+type retryableError struct {
+	err error
+}
+
+func (e retryableError) Error() string {
+	return e.err.Error()
+}
+
+// batchWriter collects the records and flushes them in background
+// with the function provided by the sink. Write blocks only if the
+// previous batches are not flushed yet.
+type batchWriter struct {
+	opts    BatchOptions
+	flushFn func(batch [][]byte) error
+	flushes chan [][]byte
+	done    chan struct{}
+	stopped sync.WaitGroup
+
+	sync.Mutex
+	batch  [][]byte
+	size   int
+	closed bool
+}
+
+func newBatchWriter(opts BatchOptions, flush func([][]byte) error) *batchWriter {
+	var w = &batchWriter{
+		opts:    opts.withDefaults(),
+		flushFn: flush,
+		flushes: make(chan [][]byte, 4),
+		done:    make(chan struct{}),
+	}
+	w.stopped.Add(1)
+	go w.loop()
+	return w
+}
+
+// Write copies the record to the current batch.
+func (w *batchWriter) Write(p []byte) (int, error) {
+	w.Lock()
+	if w.closed {
+		w.Unlock()
+		return 0, ErrWriterClosed
+	}
+	w.batch = append(w.batch, append([]byte(nil), p...))
+	w.size += len(p)
+	var batch [][]byte
+	if len(w.batch) >= w.opts.MaxRecords || w.size >= w.opts.MaxBytes {
+		batch = w.take()
+	}
+	w.Unlock()
+	if batch != nil {
+		w.flushes <- batch
+	}
+	return len(p), nil
+}
+
+// Close flushes the rest of the records and waits until all the
+// batches are flushed.
+func (w *batchWriter) Close() error {
+	w.Lock()
+	if w.closed {
+		w.Unlock()
+		return nil
+	}
+	w.closed = true
+	batch := w.take()
+	w.Unlock()
+	if batch != nil {
+		w.flushes <- batch
+	}
+	close(w.done)
+	w.stopped.Wait()
+	return nil
+}
+
+// take returns the current batch and starts a new one. It should be
+// called under the lock.
+func (w *batchWriter) take() [][]byte {
+	if len(w.batch) == 0 {
+		return nil
+	}
+	var batch = w.batch
+	w.batch = nil
+	w.size = 0
+	return batch
+}
+
+func (w *batchWriter) loop() {
+	defer w.stopped.Done()
+	var ticker = time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case batch := <-w.flushes:
+			w.flush(batch)
+		case <-ticker.C:
+			w.Lock()
+			batch := w.take()
+			w.Unlock()
+			if batch != nil {
+				w.flush(batch)
+			}
+		case <-w.done:
+			for {
+				select {
+				case batch := <-w.flushes:
+					w.flush(batch)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush calls the flush function and repeats it with exponential
+// backoff for retryable errors.
+func (w *batchWriter) flush(batch [][]byte) {
+	var (
+		backoff = w.opts.Backoff
+		err     error
+	)
+	for attempt := 0; ; attempt++ {
+		if err = w.flushFn(batch); err == nil {
+			return
+		}
+		if _, ok := err.(retryableError); !ok || attempt >= w.opts.Retries {
+			break
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	if w.opts.OnError != nil {
+		w.opts.OnError(err)
+	}
+}
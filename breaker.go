@@ -0,0 +1,195 @@
+package kiwi
+
+// This file consists of the circuit breaker for the sinks.
+
+/* Copyright (c) 2016-2020, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व */
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// BreakerState is the state of the circuit breaker of a sink.
+type BreakerState int32
+
+// States of the circuit breaker.
+const (
+	// BreakerClosed passes the records to the writer.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen skips the sink until the next probe.
+	BreakerOpen
+	// BreakerHalfOpen passes the single record to the writer for
+	// the probe.
+	BreakerHalfOpen
+)
+
+func (b BreakerState) String() string {
+	switch b {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// Default settings of the circuit breaker.
+var (
+	BreakerFailures      = 5
+	BreakerProbeInterval = 5 * time.Second
+)
+
+// ErrWriteTimeout reported as the failure of the writer when the
+// write is not completed in the timeout of the circuit breaker.
+var ErrWriteTimeout = errors.New("write timeout")
+
+// BreakerOptions defines the properties of the circuit breaker.
+// Zero values mean the defaults.
+type BreakerOptions struct {
+	// Failures is the number of consecutive failures that opens
+	// the breaker.
+	Failures int
+	// Timeout of the write after that it treated as failed. Zero
+	// value disables the timeout. Note that the timed out write
+	// still blocks the writer and the next writes fail until it
+	// completed.
+	Timeout time.Duration
+	// ProbeInterval is the delay before the probe of the writer
+	// of the opened breaker.
+	ProbeInterval time.Duration
+	// OnStateChange called when the state of the breaker changed.
+	// The last error of the writer passed if the breaker opened.
+	OnStateChange func(s *Sink, state BreakerState, err error)
+}
+
+type circuitBreaker struct {
+	opts      BreakerOptions
+	state     int32
+	failures  int32
+	nextProbe int64
+	inflight  int32
+}
+
+// WithBreaker sets the circuit breaker for the sink. The breaker
+// opens after the consecutive failures of the writer (errors or
+// timeouts). While it is open the sink skips the records and they
+// are not wait the sink. After the probe interval the next record
+// written as the probe: if it succeeded the breaker closed else it
+// stays open until the next probe.
+func (s *Sink) WithBreaker(opts BreakerOptions) *Sink {
+	if opts.Failures <= 0 {
+		opts.Failures = BreakerFailures
+	}
+	if opts.ProbeInterval <= 0 {
+		opts.ProbeInterval = BreakerProbeInterval
+	}
+	s.breaker.Store(&circuitBreaker{opts: opts})
+	return s
+}
+
+// BreakerState returns the state of the circuit breaker. For the
+// sinks without the breaker it is always BreakerClosed.
+func (s *Sink) BreakerState() BreakerState {
+	if b := s.getBreaker(); b != nil {
+		return BreakerState(atomic.LoadInt32(&b.state))
+	}
+	return BreakerClosed
+}
+
+func (s *Sink) getBreaker() *circuitBreaker {
+	b, _ := s.breaker.Load().(*circuitBreaker)
+	return b
+}
+
+// ready reports whether the sink accepts the records.
+func (b *circuitBreaker) ready() bool {
+	return BreakerState(atomic.LoadInt32(&b.state)) == BreakerClosed ||
+		time.Now().UnixNano() >= atomic.LoadInt64(&b.nextProbe)
+}
+
+// write writes the record with the timeout and updates the state of
+// the breaker accordingly with the result.
+func (b *circuitBreaker) write(s *Sink, write func([]byte) error, data []byte) {
+	if BreakerState(atomic.LoadInt32(&b.state)) == BreakerOpen {
+		b.setState(s, BreakerHalfOpen, nil)
+	}
+	var err error
+	if b.opts.Timeout > 0 {
+		err = b.timed(write, data)
+	} else {
+		err = write(data)
+	}
+	if err == nil {
+		atomic.StoreInt32(&b.failures, 0)
+		if BreakerState(atomic.LoadInt32(&b.state)) != BreakerClosed {
+			b.setState(s, BreakerClosed, nil)
+		}
+		return
+	}
+	if BreakerState(atomic.LoadInt32(&b.state)) == BreakerHalfOpen ||
+		int(atomic.AddInt32(&b.failures, 1)) >= b.opts.Failures {
+		atomic.StoreInt64(&b.nextProbe, time.Now().Add(b.opts.ProbeInterval).UnixNano())
+		b.setState(s, BreakerOpen, err)
+	}
+}
+
+func (b *circuitBreaker) timed(write func([]byte) error, data []byte) error {
+	if !atomic.CompareAndSwapInt32(&b.inflight, 0, 1) {
+		return ErrWriteTimeout
+	}
+	var (
+		result = make(chan error, 1)
+		timer  = time.NewTimer(b.opts.Timeout)
+	)
+	defer timer.Stop()
+	// The data of the formatter reused for the next record so it
+	// should be copied for the write that could outlive the call.
+	data = append([]byte(nil), data...)
+	go func() {
+		result <- write(data)
+		atomic.StoreInt32(&b.inflight, 0)
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-timer.C:
+		return ErrWriteTimeout
+	}
+}
+
+func (b *circuitBreaker) setState(s *Sink, state BreakerState, err error) {
+	if BreakerState(atomic.SwapInt32(&b.state, int32(state))) != state && b.opts.OnStateChange != nil {
+		b.opts.OnStateChange(s, state, err)
+	}
+}
@@ -0,0 +1,131 @@
+package kiwi
+
+/*
+Copyright (c) 2016-2018, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व
+
+All tests consists of three parts:
+
+- arrange structures and initialize objects for use in tests
+- act on testing object
+- check and assert on results
+
+These parts separated by empty lines in each test function.
+*/
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Test of the circuit breaker. It should open after the failures,
+// skip the sink and close after the successful probe.
+func TestBreaker_OpenAndRecover(t *testing.T) {
+	var (
+		w      = &flakyWriter{fail: true}
+		mu     sync.Mutex
+		states []BreakerState
+		log    = New()
+	)
+	out := SinkTo(w, AsLogfmt()).WithBreaker(BreakerOptions{
+		Failures:      2,
+		ProbeInterval: 50 * time.Millisecond,
+		OnStateChange: func(s *Sink, state BreakerState, err error) {
+			mu.Lock()
+			states = append(states, state)
+			mu.Unlock()
+		},
+	}).Start()
+	defer out.Close()
+
+	log.Log("n", 1)
+	log.Log("n", 2)
+	log.Log("n", 3)
+	if out.BreakerState() != BreakerOpen {
+		t.Fatalf("unexpected state %s", out.BreakerState())
+	}
+	w.setFail(false)
+	log.Log("n", 4)
+	time.Sleep(60 * time.Millisecond)
+	log.Log("n", 5)
+	log.Log("n", 6)
+
+	if out.BreakerState() != BreakerClosed {
+		t.Errorf("unexpected state %s", out.BreakerState())
+	}
+	if len(w.records) != 2 || w.records[0] != "n=5 \n" {
+		t.Errorf("unexpected records %q", w.records)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(states) != 3 || states[0] != BreakerOpen || states[1] != BreakerHalfOpen || states[2] != BreakerClosed {
+		t.Errorf("unexpected states %v", states)
+	}
+}
+
+type blockingWriter struct {
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return len(p), nil
+}
+
+// Test of the circuit breaker with blocked writer. The write should
+// be timed out and the sink should be skipped.
+func TestBreaker_Timeout(t *testing.T) {
+	var (
+		w      = &blockingWriter{release: make(chan struct{})}
+		log    = New()
+		output = bytes.NewBufferString("")
+	)
+	defer close(w.release)
+	out := SinkTo(w, AsLogfmt()).WithBreaker(BreakerOptions{Failures: 1, Timeout: 10 * time.Millisecond, ProbeInterval: time.Hour}).Start()
+	defer out.Close()
+	other := SinkTo(output, AsLogfmt()).Start()
+	defer other.Close()
+
+	start := time.Now()
+	log.Log("n", 1)
+	log.Log("n", 2)
+
+	if time.Since(start) > time.Second {
+		t.Errorf("the log was blocked for %s", time.Since(start))
+	}
+	if out.BreakerState() != BreakerOpen {
+		t.Errorf("unexpected state %s", out.BreakerState())
+	}
+	if output.String() != "n=1 \nn=2 \n" {
+		t.Errorf("unexpected output %q", output.String())
+	}
+}
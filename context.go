@@ -80,6 +80,8 @@ next:
 				l.context = append(l.context, toPair(ErrorKey, fmt.Sprintf("non a string type (%T) for the key (%v)", arg, arg)))
 				key = MessageKey
 			}
+		} else if obj, ok := arg.(ObjectMarshaler); ok && !isNilValue(obj) {
+			l.context = mergePairs(l.context, marshalPairs(key, obj))
 		} else {
 			p := toPair(key, arg)
 			for i, c := range l.context {
@@ -100,6 +102,22 @@ next:
 	return l
 }
 
+// mergePairs replaces the pairs of the context with the same keys
+// and appends the others.
+func mergePairs(context, pairs []*Pair) []*Pair {
+next:
+	for _, p := range pairs {
+		for i, c := range context {
+			if c.Key == p.Key {
+				context[i] = p
+				continue next
+			}
+		}
+		context = append(context, p)
+	}
+	return context
+}
+
 // Without drops some keys from a context for the logger. The function
 // is not concurrent safe.
 func (l *Logger) Without(keys ...string) *Logger {
@@ -0,0 +1,105 @@
+package kiwi
+
+// This file consists of the registry of the converters for custom types.
+
+/* Copyright (c) 2016-2020, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व */
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// converters keeps map[reflect.Type]func(interface{}) Pair. The map
+// is replaced on each registration so the lookups are lock free.
+var (
+	converters   atomic.Value
+	convertersMu sync.Mutex
+)
+
+// RegisterConverter sets the function that converts the values of
+// the type to the pair. The type is passed as reflect.Type or as the
+// sample value of the type:
+//
+//	kiwi.RegisterConverter(net.IP{}, func(v interface{}) kiwi.Pair {
+//		return kiwi.Pair{Val: v.(net.IP).String(), Type: kiwi.StringVal}
+//	})
+//
+// The registered converters consulted before any other conversion so
+// they allow to change the output of the types that realize Valuer,
+// Stringer or other interfaces and of the types from other packages
+// that could not be changed. The key of the returned pair is replaced
+// with the key of the value. The nil function removes the converter
+// for the type. It is safe for concurrency.
+func RegisterConverter(typ interface{}, fn func(v interface{}) Pair) {
+	t, ok := typ.(reflect.Type)
+	if !ok {
+		t = reflect.TypeOf(typ)
+	}
+	convertersMu.Lock()
+	var updated = make(map[reflect.Type]func(interface{}) Pair)
+	if current, ok := converters.Load().(map[reflect.Type]func(interface{}) Pair); ok {
+		for k, v := range current {
+			updated[k] = v
+		}
+	}
+	if fn == nil {
+		delete(updated, t)
+	} else {
+		updated[t] = fn
+	}
+	converters.Store(updated)
+	convertersMu.Unlock()
+}
+
+// converterOf returns the registered converter for the type.
+func converterOf(t reflect.Type) func(interface{}) Pair {
+	current, _ := converters.Load().(map[reflect.Type]func(interface{}) Pair)
+	if len(current) == 0 {
+		return nil
+	}
+	return current[t]
+}
+
+// convert converts the value with the registered converter. It
+// returns nil if there is no converter for the type of the value.
+func convert(key string, val interface{}) *Pair {
+	fn := converterOf(reflect.TypeOf(val))
+	if fn == nil {
+		return nil
+	}
+	var p = fn(val)
+	p.Key = key
+	if p.Raw == nil && p.Eval == nil {
+		p.Raw = rawValue(val)
+	}
+	return &p
+}
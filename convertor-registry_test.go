@@ -0,0 +1,138 @@
+package kiwi
+
+/*
+Copyright (c) 2016-2018, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व
+
+All tests consists of three parts:
+
+- arrange structures and initialize objects for use in tests
+- act on testing object
+- check and assert on results
+
+These parts separated by empty lines in each test function.
+*/
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type registryID [2]byte
+
+// Test of the converter registered for the type that realizes
+// Stringer. The converter should have priority.
+func TestConvertorRegistry_Override_Logfmt(t *testing.T) {
+	output := bytes.NewBufferString("")
+	log := New()
+	out := SinkTo(output, AsLogfmt()).Start()
+	RegisterConverter(net.IP{}, func(v interface{}) Pair {
+		return Pair{Val: "ip:" + v.(net.IP).String(), Type: CustomUnquoted}
+	})
+	defer RegisterConverter(net.IP{}, nil)
+
+	log.Log("addr", net.IPv4(127, 0, 0, 1))
+
+	out.Close()
+	if strings.TrimSpace(output.String()) != `addr=ip:127.0.0.1` {
+		println(output.String())
+		t.Fail()
+	}
+}
+
+// Test of the converter registered by reflect.Type. The values nested
+// in the structured values should be converted too.
+func TestConvertorRegistry_ByType(t *testing.T) {
+	RegisterConverter(reflect.TypeOf(registryID{}), func(v interface{}) Pair {
+		id := v.(registryID)
+		return Pair{Val: string([]byte{'a' + id[0], 'a' + id[1]}), Type: StringVal}
+	})
+	defer RegisterConverter(reflect.TypeOf(registryID{}), nil)
+
+	p := toPair("id", registryID{1, 2})
+	nested := toPair("ids", []registryID{{0, 0}})
+
+	if p.Key != "id" || p.Val != "bc" || p.Raw != nil || nested.Val != `["aa"]` {
+		t.Fail()
+	}
+}
+
+// Test of the concurrent registration. It should be run with -race.
+func TestConvertorRegistry_Concurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	type local int
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			RegisterConverter(local(0), func(v interface{}) Pair { return Pair{Val: "x"} })
+			toPair("k", local(1))
+		}()
+	}
+
+	wg.Wait()
+
+	RegisterConverter(local(0), nil)
+	if toPair("k", local(1)).Val != "1" {
+		t.Fail()
+	}
+}
+
+type registryCode int
+
+func (c registryCode) String() string {
+	return "code-" + strconv.Itoa(int(c))
+}
+
+// Test of the typed filter for the value of the custom type. The
+// filter should check the value converted by the converter.
+func TestConvertorRegistry_TypedFilter_Logfmt(t *testing.T) {
+	output := bytes.NewBufferString("")
+	log := New()
+	out := SinkTo(output, AsLogfmt()).Int64Range("code", 0, 10).Start()
+	RegisterConverter(registryCode(0), func(v interface{}) Pair {
+		return Pair{Val: strconv.Itoa(int(v.(registryCode))), Type: IntegerVal}
+	})
+	defer RegisterConverter(registryCode(0), nil)
+
+	log.Log("code", registryCode(5), "n", 1)
+	log.Log("code", registryCode(50), "n", 2)
+
+	out.Close()
+	if strings.TrimSpace(output.String()) != `code=5 n=1` {
+		println(output.String())
+		t.Fail()
+	}
+}
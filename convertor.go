@@ -35,6 +35,7 @@ OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 import (
 	"encoding"
 	"fmt"
+	"reflect"
 	"strconv"
 	"time"
 )
@@ -53,6 +54,15 @@ const (
 	StringVal
 	TimeVal
 	CustomQuoted
+	// ErrorVal is the value of the error. It keeps JSON object
+	// with the message, the type, the causes and the stack trace
+	// of the error. Formatters could output it as the nested object
+	// or flatten it to several pairs.
+	ErrorVal
+	// ObjectVal is the structured value (map, slice or struct)
+	// kept as JSON. Formatters could output it as the nested
+	// object or flatten it to the pairs with dotted keys.
+	ObjectVal
 )
 
 // FloatFormat used in Float to String conversion.
@@ -62,66 +72,101 @@ var FloatFormat byte = 'e'
 // TimeLayout used in time.Time to String conversion.
 var TimeLayout = time.RFC3339
 
+// rawValue returns the value if it could be kept in the pair as the
+// original typed value. Only the immutable scalars and the time are
+// kept because the sinks read them on their own goroutines.
+func rawValue(val interface{}) interface{} {
+	if _, ok := val.(time.Time); ok {
+		return val
+	}
+	switch reflect.TypeOf(val).Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return val
+	}
+	return nil
+}
+
 // it applicable for all scalar types and for strings
 func toPair(key string, val interface{}) *Pair {
+	if p := convert(key, val); p != nil {
+		return p
+	}
 	switch v := val.(type) {
 	case string:
-		return &Pair{key, v, nil, StringVal}
+		return &Pair{Key: key, Val: v, Type: StringVal, Raw: val}
 	case []byte:
-		return &Pair{key, string(v), nil, StringVal}
+		return &Pair{Key: key, Val: string(v), Type: StringVal}
 	case bool:
 		if val.(bool) {
-			return &Pair{key, "true", nil, BooleanVal}
+			return &Pair{Key: key, Val: "true", Type: BooleanVal, Raw: val}
 		}
-		return &Pair{key, "false", nil, BooleanVal}
+		return &Pair{Key: key, Val: "false", Type: BooleanVal, Raw: val}
 	case int:
-		return &Pair{key, strconv.Itoa(v), nil, IntegerVal}
+		return &Pair{Key: key, Val: strconv.Itoa(v), Type: IntegerVal, Raw: val}
 	case int8:
-		return &Pair{key, strconv.FormatInt(int64(v), 10), nil, IntegerVal}
+		return &Pair{Key: key, Val: strconv.FormatInt(int64(v), 10), Type: IntegerVal, Raw: val}
 	case int16:
-		return &Pair{key, strconv.FormatInt(int64(v), 10), nil, IntegerVal}
+		return &Pair{Key: key, Val: strconv.FormatInt(int64(v), 10), Type: IntegerVal, Raw: val}
 	case int32:
-		return &Pair{key, strconv.FormatInt(int64(v), 10), nil, IntegerVal}
+		return &Pair{Key: key, Val: strconv.FormatInt(int64(v), 10), Type: IntegerVal, Raw: val}
 	case int64:
-		return &Pair{key, strconv.FormatInt(v, 10), nil, IntegerVal}
+		return &Pair{Key: key, Val: strconv.FormatInt(v, 10), Type: IntegerVal, Raw: val}
 	case uint:
-		return &Pair{key, strconv.FormatUint(uint64(v), 10), nil, IntegerVal}
+		return &Pair{Key: key, Val: strconv.FormatUint(uint64(v), 10), Type: IntegerVal, Raw: val}
 	case uint8:
-		return &Pair{key, strconv.FormatUint(uint64(v), 10), nil, IntegerVal}
+		return &Pair{Key: key, Val: strconv.FormatUint(uint64(v), 10), Type: IntegerVal, Raw: val}
 	case uint16:
-		return &Pair{key, strconv.FormatUint(uint64(v), 10), nil, IntegerVal}
+		return &Pair{Key: key, Val: strconv.FormatUint(uint64(v), 10), Type: IntegerVal, Raw: val}
 	case uint32:
-		return &Pair{key, strconv.FormatUint(uint64(v), 10), nil, IntegerVal}
+		return &Pair{Key: key, Val: strconv.FormatUint(uint64(v), 10), Type: IntegerVal, Raw: val}
 	case uint64:
-		return &Pair{key, strconv.FormatUint(v, 10), nil, IntegerVal}
+		return &Pair{Key: key, Val: strconv.FormatUint(v, 10), Type: IntegerVal, Raw: val}
 	case float32:
-		return &Pair{key, strconv.FormatFloat(float64(v), FloatFormat, -1, 32), nil, FloatVal}
+		return &Pair{Key: key, Val: strconv.FormatFloat(float64(v), FloatFormat, -1, 32), Type: FloatVal, Raw: val}
 	case float64:
-		return &Pair{key, strconv.FormatFloat(v, FloatFormat, -1, 64), nil, FloatVal}
+		return &Pair{Key: key, Val: strconv.FormatFloat(v, FloatFormat, -1, 64), Type: FloatVal, Raw: val}
 	case complex64:
-		return &Pair{key, fmt.Sprintf("%f", v), nil, ComplexVal}
+		return &Pair{Key: key, Val: fmt.Sprintf("%f", v), Type: ComplexVal, Raw: val}
 	case complex128:
-		return &Pair{key, fmt.Sprintf("%f", v), nil, ComplexVal}
+		return &Pair{Key: key, Val: fmt.Sprintf("%f", v), Type: ComplexVal, Raw: val}
 	case time.Time:
-		return &Pair{key, v.Format(TimeLayout), nil, TimeVal}
+		return &Pair{Key: key, Val: v.Format(TimeLayout), Type: TimeVal, Raw: val}
+	case ObjectMarshaler:
+		if isNilValue(v) {
+			return &Pair{Key: key, Val: "<nil>", Type: StringVal}
+		}
+		return &Pair{Key: key, Val: marshalJSON(v), Type: ObjectVal}
 	case Valuer:
 		var pairType = CustomUnquoted
 		if v.IsQuoted() {
 			pairType = CustomQuoted
 		}
-		return &Pair{key, v.String(), nil, pairType}
+		return &Pair{Key: key, Val: v.String(), Type: pairType}
+	case error:
+		// The typed nil could not be asked for the message.
+		if isNilValue(v) {
+			return &Pair{Key: key, Val: "<nil>", Type: StringVal}
+		}
+		return &Pair{Key: key, Val: errorToJSON(v), Type: ErrorVal}
 	case Stringer:
-		return &Pair{key, v.String(), nil, StringVal}
+		return &Pair{Key: key, Val: v.String(), Type: StringVal}
 	case encoding.TextMarshaler:
 		data, err := v.MarshalText()
 		if err != nil {
-			return &Pair{key, fmt.Sprintf("%s", err), nil, StringVal}
+			return &Pair{Key: key, Val: fmt.Sprintf("%s", err), Type: StringVal}
 		}
-		return &Pair{key, string(data), nil, StringVal}
-	case func() string:
-		return &Pair{key, "", v, StringVal}
+		return &Pair{Key: key, Val: string(data), Type: StringVal}
 	default:
+		if valType, ok := lazyType(val); ok {
+			return &Pair{Key: key, Eval: val, Type: valType}
+		}
+		if isObject(val) {
+			return &Pair{Key: key, Val: objectToJSON(val), Type: ObjectVal}
+		}
 		// Worst case conversion that depends on reflection.
-		return &Pair{key, fmt.Sprintf("%+v", val), nil, StringVal}
+		return &Pair{Key: key, Val: fmt.Sprintf("%+v", val), Type: StringVal}
 	}
 }
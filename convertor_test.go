@@ -310,3 +310,26 @@ func TestConvertor_LogValueWithoutKey(t *testing.T) {
 		t.Fail()
 	}
 }
+
+type convertorLevel int
+
+func (l convertorLevel) String() string {
+	return "level-" + strings.Repeat("i", int(l))
+}
+
+// Test of the original values kept in the pairs. Only the immutable
+// scalars and the time should be kept because the sinks read them on
+// their own goroutines.
+func TestConvertor_RawValues(t *testing.T) {
+	at := time.Now()
+
+	scalar := toPair("k", 42)
+	moment := toPair("k", at)
+	object := toPair("k", map[string]int{"a": 1})
+	pointer := toPair("k", &struct{ A int }{1})
+	stringer := toPair("k", convertorLevel(2))
+
+	if scalar.Raw != 42 || moment.Raw != at || object.Raw != nil || pointer.Raw != nil || stringer.Raw != nil {
+		t.Fail()
+	}
+}
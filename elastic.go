@@ -0,0 +1,288 @@
+package kiwi
+
+// This file consists of the sink for Elasticsearch and OpenSearch bulk API.
+
+/* Copyright (c) 2016-2020, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व */
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ElasticOptions defines the properties of Elasticsearch sink.
+type ElasticOptions struct {
+	HTTPOptions
+	// Index is the name of the index for the records. The part in
+	// braces is the layout of the current date in UTC (in the
+	// format of time.Format()): "logs-{2006.01.02}" becomes
+	// "logs-2020.01.02". Default is "kiwi-{2006.01.02}".
+	Index string
+}
+
+// ElasticItemError reported by OnError callback for each document
+// that rejected by Elasticsearch.
+type ElasticItemError struct {
+	Index    string
+	Status   int
+	Type     string
+	Reason   string
+	Document []byte
+}
+
+func (e *ElasticItemError) Error() string {
+	return fmt.Sprintf("elastic index %s: status %d: %s: %s", e.Index, e.Status, e.Type, e.Reason)
+}
+
+// SinkToElastic creates a new sink that writes the records to
+// Elasticsearch or OpenSearch with the bulk API. The url is the
+// address of the server or the cluster. Each record becomes a JSON
+// document with the fields typed accordingly with type hints of the
+// values and "@timestamp" field with the time of the record. The
+// records are sent in batches. The documents rejected with 429 or 5xx
+// statuses are retried, other rejected documents are reported with
+// ElasticItemError to OnError callback. The batch is not repeated
+// after the partial failure, the documents that could not be sent
+// then are reported the same way.
+//
+// As for SinkTo() the sink should be started explicitly.
+func SinkToElastic(url string, opts ElasticOptions) *Sink {
+	var c = &httpClient{url: strings.TrimRight(url, "/") + "/_bulk", opts: opts.HTTPOptions}
+	if c.opts.Client == nil {
+		c.opts.Client = http.DefaultClient
+	}
+	var w = &elasticWriter{client: c, opts: opts.BatchOptions.withDefaults()}
+	w.index, w.layout = parseIndexPattern(opts.Index)
+	w.batch = newBatchWriter(opts.BatchOptions, w.bulk)
+	var sink = SinkTo(w, &formatElastic{line: bytes.NewBuffer(make([]byte, 512))})
+	sink.closer = w.batch
+	return sink
+}
+
+// parseIndexPattern splits the pattern to the prefix, the date layout
+// and the suffix.
+func parseIndexPattern(pattern string) ([2]string, string) {
+	if pattern == "" {
+		pattern = "kiwi-{2006.01.02}"
+	}
+	var (
+		start = strings.IndexByte(pattern, '{')
+		end   = strings.IndexByte(pattern, '}')
+	)
+	if start < 0 || end < start {
+		return [2]string{pattern, ""}, ""
+	}
+	return [2]string{pattern[:start], pattern[end+1:]}, pattern[start+1 : end]
+}
+
+type formatElastic struct {
+	line *bytes.Buffer
+}
+
+func (f *formatElastic) Begin() {
+	f.line.Reset()
+	f.line.WriteString(`{"@timestamp":"`)
+	f.line.WriteString(time.Now().UTC().Format(time.RFC3339Nano))
+	f.line.WriteRune('"')
+}
+
+func (f *formatElastic) Pair(key, val string, valType int) {
+	f.TypedPair(key, val, nil, valType)
+}
+
+func (f *formatElastic) TypedPair(key, val string, raw interface{}, valType int) {
+	f.line.WriteRune(',')
+	writeJSONString(f.line, key)
+	f.line.WriteRune(':')
+	switch valType {
+	case BooleanVal:
+		if val == "true" || val == "false" {
+			f.line.WriteString(val)
+			return
+		}
+	case IntegerVal:
+		if _, err := strconv.ParseInt(val, 10, 64); err == nil {
+			f.line.WriteString(val)
+			return
+		}
+		if _, err := strconv.ParseUint(val, 10, 64); err == nil {
+			f.line.WriteString(val)
+			return
+		}
+	case FloatVal:
+		if v, err := strconv.ParseFloat(val, 64); err == nil && !math.IsInf(v, 0) && !math.IsNaN(v) {
+			f.line.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+			return
+		}
+	case TimeVal:
+		if v, ok := pairTime(val, raw); ok {
+			writeJSONString(f.line, v.Format(time.RFC3339Nano))
+			return
+		}
+	case ErrorVal, ObjectVal:
+		f.line.WriteString(val)
+		return
+	}
+	writeJSONString(f.line, val)
+}
+
+func (f *formatElastic) Finish() []byte {
+	f.line.WriteRune('}')
+	return f.line.Bytes()
+}
+
+// elasticWriter passes the documents to the batch prefixed with the
+// name of the index.
+type elasticWriter struct {
+	client *httpClient
+	batch  *batchWriter
+	opts   BatchOptions
+	index  [2]string
+	layout string
+	entry  bytes.Buffer
+	body   bytes.Buffer
+}
+
+type elasticResponse struct {
+	Errors bool
+	Items  []map[string]struct {
+		Status int
+		Error  struct {
+			Type   string
+			Reason string
+		}
+	}
+}
+
+func (w *elasticWriter) Write(p []byte) (int, error) {
+	w.entry.Reset()
+	w.entry.WriteString(w.index[0])
+	if w.layout != "" {
+		w.entry.WriteString(time.Now().UTC().Format(w.layout))
+	}
+	w.entry.WriteString(w.index[1])
+	w.entry.WriteRune('\n')
+	w.entry.Write(p)
+	return w.batch.Write(w.entry.Bytes())
+}
+
+// bulk sends the documents and repeats sending of the documents
+// rejected with retryable statuses. The failed request returned as
+// the error only until the server responded for the items, later
+// the documents that are not indexed are reported and the batch is
+// not repeated because its part is indexed already.
+func (w *elasticWriter) bulk(batch [][]byte) error {
+	var (
+		backoff   = w.opts.Backoff
+		responded bool
+	)
+	for attempt := 0; ; attempt++ {
+		w.body.Reset()
+		for _, entry := range batch {
+			sep := bytes.IndexByte(entry, '\n')
+			w.body.WriteString(`{"index":{"_index":`)
+			writeJSONString(&w.body, string(entry[:sep]))
+			w.body.WriteString("}}\n")
+			w.body.Write(entry[sep+1:])
+			w.body.WriteRune('\n')
+		}
+		var resp elasticResponse
+		data, err := w.client.do(w.body.Bytes(), "application/x-ndjson")
+		if err == nil {
+			err = json.Unmarshal(data, &resp)
+		}
+		if err != nil {
+			if !responded {
+				return err
+			}
+			if _, ok := err.(retryableError); !ok || attempt >= w.opts.Retries {
+				for _, entry := range batch {
+					w.report(entry, 0, "request_failed", err.Error())
+				}
+				return nil
+			}
+			time.Sleep(backoff)
+			backoff *= 2
+			continue
+		}
+		if !resp.Errors {
+			return nil
+		}
+		responded = true
+		var retry [][]byte
+		for i, item := range resp.Items {
+			if i >= len(batch) {
+				break
+			}
+			for _, result := range item {
+				if result.Status < 300 {
+					continue
+				}
+				if result.Status == http.StatusTooManyRequests || result.Status >= 500 {
+					retry = append(retry, batch[i])
+					continue
+				}
+				w.report(batch[i], result.Status, result.Error.Type, result.Error.Reason)
+			}
+		}
+		if len(retry) == 0 {
+			return nil
+		}
+		if attempt >= w.opts.Retries {
+			for _, entry := range retry {
+				w.report(entry, 0, "retries_exhausted", "the document was rejected after all the retries")
+			}
+			return nil
+		}
+		batch = retry
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (w *elasticWriter) report(entry []byte, status int, errType, reason string) {
+	if w.opts.OnError == nil {
+		return
+	}
+	sep := bytes.IndexByte(entry, '\n')
+	w.opts.OnError(&ElasticItemError{
+		Index:    string(entry[:sep]),
+		Status:   status,
+		Type:     errType,
+		Reason:   reason,
+		Document: append([]byte(nil), entry[sep+1:]...),
+	})
+}
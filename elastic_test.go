@@ -0,0 +1,193 @@
+package kiwi
+
+/*
+Copyright (c) 2016-2018, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व
+
+All tests consists of three parts:
+
+- arrange structures and initialize objects for use in tests
+- act on testing object
+- check and assert on results
+
+These parts separated by empty lines in each test function.
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Test of Elasticsearch sink with a fake bulk endpoint. Only the
+// documents rejected with retryable status should be sent again and
+// the other failures should be reported.
+func TestElastic_Bulk(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests [][]map[string]interface{}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_bulk" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var lines []map[string]interface{}
+		s := bufio.NewScanner(r.Body)
+		for s.Scan() {
+			var v map[string]interface{}
+			if err := json.Unmarshal(s.Bytes(), &v); err != nil {
+				t.Error(err)
+			}
+			lines = append(lines, v)
+		}
+		mu.Lock()
+		requests = append(requests, lines)
+		first := len(requests) == 1
+		mu.Unlock()
+		if first {
+			w.Write([]byte(`{"errors":true,"items":[
+{"index":{"status":201}},
+{"index":{"status":429,"error":{"type":"es_rejected_execution_exception","reason":"busy"}}},
+{"index":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"bad field"}}}]}`))
+			return
+		}
+		w.Write([]byte(`{"errors":false,"items":[{"index":{"status":201}}]}`))
+	}))
+	defer srv.Close()
+	var errs []error
+	log := New()
+	out := SinkToElastic(srv.URL, ElasticOptions{
+		HTTPOptions: HTTPOptions{BatchOptions: BatchOptions{
+			Interval: time.Hour,
+			Backoff:  time.Millisecond,
+			OnError:  func(err error) { errs = append(errs, err) },
+		}},
+		Index: "logs-{2006}-test",
+	}).Start()
+
+	log.Log("n", 1, "f", 0.5, "ok", true)
+	log.Log("n", 2)
+	log.Log("n", 3)
+
+	out.Close()
+	if len(requests) != 2 || len(requests[0]) != 6 || len(requests[1]) != 2 {
+		t.Fatalf("unexpected requests %v", requests)
+	}
+	index := requests[0][0]["index"].(map[string]interface{})["_index"]
+	if index != "logs-"+time.Now().UTC().Format("2006")+"-test" {
+		t.Errorf("unexpected index %v", index)
+	}
+	doc := requests[0][1]
+	if doc["n"] != 1.0 || doc["f"] != 0.5 || doc["ok"] != true || doc["@timestamp"] == nil {
+		t.Errorf("unexpected document %v", doc)
+	}
+	if requests[1][1]["n"] != 2.0 {
+		t.Errorf("unexpected retry %v", requests[1])
+	}
+	if len(errs) != 1 {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	if e, ok := errs[0].(*ElasticItemError); !ok || e.Status != 400 || string(e.Document) == "" {
+		t.Errorf("unexpected error %v", errs[0])
+	}
+}
+
+// Test of Elasticsearch formatter with the time formatted by the
+// layout of the sink. The time should be output from the original
+// value in RFC3339 format.
+func TestElastic_TypedTime(t *testing.T) {
+	f := &formatElastic{line: bytes.NewBuffer(nil)}
+	at := time.Date(2020, 1, 2, 3, 4, 5, 6000, time.UTC)
+	var doc map[string]interface{}
+
+	f.Begin()
+	f.TypedPair("t", at.Format(time.Kitchen), at, TimeVal)
+	err := json.Unmarshal(f.Finish(), &doc)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc["t"] != "2020-01-02T03:04:05.000006Z" {
+		t.Errorf("unexpected document %v", doc)
+	}
+}
+
+// Test of Elasticsearch sink with the failed request after the
+// partial failure. The indexed documents should not be sent again
+// and the rest should be reported.
+func TestElastic_PartialThenUnavailable(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		first := requests == 1
+		mu.Unlock()
+		if first {
+			w.Write([]byte(`{"errors":true,"items":[
+{"index":{"status":201}},
+{"index":{"status":429,"error":{"type":"es_rejected_execution_exception","reason":"busy"}}}]}`))
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	var errs []error
+	log := New()
+	out := SinkToElastic(srv.URL, ElasticOptions{
+		HTTPOptions: HTTPOptions{BatchOptions: BatchOptions{
+			Interval: time.Hour,
+			Retries:  2,
+			Backoff:  time.Millisecond,
+			OnError:  func(err error) { errs = append(errs, err) },
+		}},
+	}).Start()
+
+	log.Log("n", 1)
+	log.Log("n", 2)
+
+	out.Close()
+	if requests != 3 {
+		t.Errorf("unexpected number of requests %d", requests)
+	}
+	if len(errs) != 1 {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	if e, ok := errs[0].(*ElasticItemError); !ok || e.Type != "request_failed" || !bytes.Contains(e.Document, []byte(`"n":2`)) {
+		t.Errorf("unexpected error %v", errs[0])
+	}
+}
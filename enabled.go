@@ -0,0 +1,163 @@
+package kiwi
+
+// This file consists of the checks whether the records would be logged.
+
+/* Copyright (c) 2016-2020, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व */
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Enabled reports whether the record with the key-value pairs and
+// the context of the global logger would be accepted by any of the
+// active sinks. It allows to skip the preparation of the expensive
+// values:
+//
+//	if kiwi.Enabled("level", "debug") {
+//		kiwi.Log("level", "debug", "dump", dumpState())
+//	}
+//
+// The values are checked with the filters of the sinks. The delayed
+// values are not evaluated and not checked.
+func Enabled(keyVals ...interface{}) bool {
+	if atomic.LoadInt32(&activeSinks) == 0 {
+		return false
+	}
+	global.RLock()
+	var record = append(make([]*Pair, 0, len(context)+len(keyVals)), context...)
+	global.RUnlock()
+	return acceptingSinks(appendPairs(record, keyVals), false) != nil
+}
+
+// Enabled reports whether the record with the key-value pairs, the
+// context of the logger and the pairs added by Add() would be
+// accepted by any of the active sinks. See the global Enabled().
+func (l *Logger) Enabled(keyVals ...interface{}) bool {
+	if atomic.LoadInt32(&activeSinks) == 0 {
+		return false
+	}
+	var record = make([]*Pair, 0, len(l.context)+len(l.pairs)+len(keyVals))
+	record = append(record, l.context...)
+	record = append(record, l.pairs...)
+	return acceptingSinks(appendPairs(record, keyVals), false) != nil
+}
+
+// acceptingSinks returns the active sinks that accept the record
+// before evaluation of its delayed values. If all is false it stops
+// on the first of them.
+func acceptingSinks(record []*Pair, all bool) []*Sink {
+	var sinks []*Sink
+	collector.RLock()
+	defer collector.RUnlock()
+	for _, s := range collector.sinks {
+		if atomic.LoadInt32(s.state) != sinkActive {
+			continue
+		}
+		s.RLock()
+		ok := s.accepts(record, checkResolved)
+		s.RUnlock()
+		if ok {
+			if sinks = append(sinks, s); !all {
+				break
+			}
+		}
+	}
+	return sinks
+}
+
+// delayedSinks checks the record with the delayed values before
+// their evaluation and returns the sinks that accept it. It returns
+// false if no sink accepts the record. The record without the
+// delayed values is not checked here because the sinks check it on
+// their own.
+func delayedSinks(record []*Pair) ([]*Sink, bool) {
+	if !hasDelayed(record) {
+		return nil, true
+	}
+	sinks := acceptingSinks(record, true)
+	return sinks, sinks != nil
+}
+
+// hasDelayed reports whether the record has the delayed values.
+func hasDelayed(record []*Pair) bool {
+	for _, p := range record {
+		if p.Eval != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSink reports whether the sink is in the list.
+func hasSink(sinks []*Sink, s *Sink) bool {
+	for _, v := range sinks {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// appendPairs converts the key-value pairs and appends them to the
+// record. The value without the key is added as the message.
+func appendPairs(pairs []*Pair, keyVals []interface{}) []*Pair {
+	var (
+		key          string
+		shouldBeAKey = true
+	)
+	for _, val := range keyVals {
+		if shouldBeAKey {
+			switch v := val.(type) {
+			case string:
+				key = v
+			case *Pair:
+				pairs = append(pairs, v)
+				continue
+			case []*Pair:
+				pairs = append(pairs, v...)
+				continue
+			default:
+				pairs = append(pairs, toPair(ErrorKey, fmt.Sprintf("non a string type (%T) for the key (%v)", val, val)))
+				key = MessageKey
+			}
+		} else if obj, ok := val.(ObjectMarshaler); ok && !isNilValue(obj) {
+			pairs = append(pairs, marshalPairs(key, obj)...)
+		} else {
+			pairs = append(pairs, toPair(key, val))
+		}
+		shouldBeAKey = !shouldBeAKey
+	}
+	if !shouldBeAKey && key != MessageKey {
+		pairs = append(pairs, toPair(MessageKey, key))
+	}
+	return pairs
+}
@@ -0,0 +1,134 @@
+package kiwi
+
+/*
+Copyright (c) 2016-2018, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व
+
+All tests consists of three parts:
+
+- arrange structures and initialize objects for use in tests
+- act on testing object
+- check and assert on results
+
+These parts separated by empty lines in each test function.
+*/
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// Test of the record without the active sinks. It should not be
+// enabled and the delayed values should not be evaluated.
+func TestEnabled_NoActiveSinks(t *testing.T) {
+	output := bytes.NewBufferString("")
+	log := New()
+	out := SinkTo(output, AsLogfmt())
+	var evaluated bool
+
+	enabled := log.Enabled("k", 1)
+	log.Log("k", func() string { evaluated = true; return "v" })
+
+	out.Close()
+	if enabled || evaluated || output.Len() != 0 {
+		t.Fail()
+	}
+}
+
+// Test of the record checked with the filters of the sink.
+func TestEnabled_Filters(t *testing.T) {
+	output := bytes.NewBufferString("")
+	log := New()
+	out := SinkTo(output, AsLogfmt()).HasValue("level", "info", "error").Start()
+
+	debug := log.Enabled("level", "debug", "msg", "x")
+	info := log.Enabled("level", "info")
+	lazy := Enabled("level", func() string { return "debug" })
+
+	out.Close()
+	if debug || !info || !lazy {
+		t.Fail()
+	}
+}
+
+// Test of the record rejected by the filters of the sink. The delayed
+// values should not be evaluated for it.
+func TestEnabled_RejectedNotEvaluated(t *testing.T) {
+	output := bytes.NewBufferString("")
+	log := New()
+	out := SinkTo(output, AsLogfmt()).HasValue("level", "info").Start()
+	var evaluated bool
+
+	log.Log("level", "debug", "x", func() string { evaluated = true; return "v" })
+	Log("level", "debug", "x", func() string { evaluated = true; return "v" })
+
+	out.Close()
+	if evaluated || output.Len() != 0 {
+		println(output.String())
+		t.Fail()
+	}
+}
+
+// Test of the delayed value evaluated to the empty string. It should
+// be checked with the filters as any other value.
+func TestEnabled_EmptyLazyFiltered(t *testing.T) {
+	output := bytes.NewBufferString("")
+	log := New()
+	out := SinkTo(output, AsLogfmt()).HasValue("level", "info").Start()
+
+	log.Log("level", func() string { return "" }, "msg", "x")
+
+	out.Close()
+	if output.Len() != 0 {
+		println(output.String())
+		t.Fail()
+	}
+}
+
+// Test of the record with the delayed value checked by several
+// sinks. Only the sink that accepted the record before evaluation
+// should get it.
+func TestEnabled_DelayedToAcceptedSinks(t *testing.T) {
+	info := bytes.NewBufferString("")
+	debug := bytes.NewBufferString("")
+	log := New()
+	infoOut := SinkTo(info, AsLogfmt()).HasValue("level", "info").Start()
+	debugOut := SinkTo(debug, AsLogfmt()).HasValue("level", "debug").Start()
+
+	log.Log("level", "debug", "x", func() string { return "v" })
+
+	infoOut.Close()
+	debugOut.Close()
+	if info.Len() != 0 || strings.TrimSpace(debug.String()) != `level="debug" x="v"` {
+		println(info.String(), debug.String())
+		t.Fail()
+	}
+}
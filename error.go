@@ -0,0 +1,145 @@
+package kiwi
+
+// Conversion of the errors to the structured values.
+
+/* Copyright (c) 2016-2020, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व */
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// MaxErrorCauses limits the number of the causes of the error walked
+// by the logger.
+var MaxErrorCauses = 32
+
+// errorToJSON converts the error to JSON object with the message, the
+// type, the list of the causes and the stack trace if the error or
+// any of its causes provides it. The causes collected from the chain
+// of Unwrap() methods including the errors joined with errors.Join().
+func errorToJSON(err error) string {
+	var (
+		buf    bytes.Buffer
+		causes []error
+		stack  string
+	)
+	buf.WriteString(`{"message":`)
+	writeJSONString(&buf, err.Error())
+	buf.WriteString(`,"type":`)
+	writeJSONString(&buf, fmt.Sprintf("%T", err))
+	if s := errorStack(err); s != "" {
+		stack = s
+	}
+	causes = collectCauses(err, causes)
+	if len(causes) > 0 {
+		buf.WriteString(`,"causes":[`)
+		for i, cause := range causes {
+			if i > 0 {
+				buf.WriteRune(',')
+			}
+			buf.WriteString(`{"message":`)
+			writeJSONString(&buf, cause.Error())
+			buf.WriteString(`,"type":`)
+			writeJSONString(&buf, fmt.Sprintf("%T", cause))
+			buf.WriteRune('}')
+			// The deepest stack is the closest to the origin
+			// of the error.
+			if s := errorStack(cause); s != "" {
+				stack = s
+			}
+		}
+		buf.WriteRune(']')
+	}
+	if stack != "" {
+		buf.WriteString(`,"stack":`)
+		writeJSONString(&buf, stack)
+	}
+	buf.WriteRune('}')
+	return buf.String()
+}
+
+// collectCauses walks the tree of the wrapped errors depth first.
+func collectCauses(err error, causes []error) []error {
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		for _, cause := range e.Unwrap() {
+			if isNilValue(cause) || len(causes) >= MaxErrorCauses {
+				continue
+			}
+			causes = collectCauses(cause, append(causes, cause))
+		}
+	default:
+		if cause := errors.Unwrap(err); !isNilValue(cause) && len(causes) < MaxErrorCauses {
+			causes = collectCauses(cause, append(causes, cause))
+		}
+	}
+	return causes
+}
+
+// errorStack returns the stack trace of the error if it provides
+// one. The errors created by github.com/pkg/errors (StackTrace()
+// method) and the errors with Stack() method returning []byte or
+// string are recognized.
+func errorStack(err error) string {
+	switch e := err.(type) {
+	case interface{ Stack() []byte }:
+		return string(e.Stack())
+	case interface{ Stack() string }:
+		return e.Stack()
+	}
+	// The type of the stack trace belongs to the package of the
+	// error so it is checked with reflection.
+	var m = reflect.ValueOf(err).MethodByName("StackTrace")
+	if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+		return ""
+	}
+	var st = m.Call(nil)[0]
+	if st.Kind() == reflect.Slice && st.Len() == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%+v", st.Interface())
+}
+
+// isNilValue reports whether the value is nil or the typed nil (the
+// nil pointer, map, slice, function or channel in the interface).
+func isNilValue(val interface{}) bool {
+	if val == nil {
+		return true
+	}
+	var v = reflect.ValueOf(val)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package kiwi
+
+/*
+Copyright (c) 2016-2018, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व
+
+All tests consists of three parts:
+
+- arrange structures and initialize objects for use in tests
+- act on testing object
+- check and assert on results
+
+These parts separated by empty lines in each test function.
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type stackError struct{ msg string }
+
+func (e stackError) Error() string { return e.msg }
+func (e stackError) Stack() []byte { return []byte("main.go:42") }
+
+type joinedError []error
+
+func (e joinedError) Error() string   { return "joined" }
+func (e joinedError) Unwrap() []error { return e }
+
+// Test of the error value in JSON. It should be embedded as the object
+// with the chain of the causes and the stack of the deepest error.
+func TestError_WrappedChain_JSON(t *testing.T) {
+	output := bytes.NewBufferString("")
+	log := New()
+	out := SinkTo(output, AsJSON()).Start()
+	err := fmt.Errorf("read config: %w", stackError{"no such file"})
+
+	log.Log("err", err)
+
+	out.Close()
+	var record struct {
+		Err struct {
+			Message string
+			Type    string
+			Causes  []struct{ Message, Type string }
+			Stack   string
+		}
+	}
+	if e := json.Unmarshal(output.Bytes(), &record); e != nil {
+		t.Fatal(e, output.String())
+	}
+	if record.Err.Message != "read config: no such file" ||
+		len(record.Err.Causes) != 1 ||
+		record.Err.Causes[0].Message != "no such file" ||
+		record.Err.Causes[0].Type != "kiwi.stackError" ||
+		record.Err.Stack != "main.go:42" {
+		println(output.String())
+		t.Fail()
+	}
+}
+
+// Test of the joined errors in logfmt. The message should be output
+// with the key as is and the causes as the dotted keys.
+func TestError_Joined_Logfmt(t *testing.T) {
+	output := bytes.NewBufferString("")
+	log := New()
+	out := SinkTo(output, AsLogfmt()).Start()
+	err := joinedError{errors.New("first"), errors.New("second")}
+
+	log.Log("err", err)
+
+	out.Close()
+	expected := `err="joined" err.type="kiwi.joinedError" ` +
+		`err.causes.0.message="first" err.causes.0.type="*errors.errorString" ` +
+		`err.causes.1.message="second" err.causes.1.type="*errors.errorString"`
+	if strings.TrimSpace(output.String()) != expected {
+		println(output.String())
+		t.Fail()
+	}
+}
+
+type nilError struct{ msg string }
+
+func (e *nilError) Error() string { return e.msg }
+
+// Test of the typed nil error. It should be output as <nil> instead
+// of the panic on the call of Error().
+func TestError_TypedNil_Logfmt(t *testing.T) {
+	output := bytes.NewBufferString("")
+	log := New()
+	out := SinkTo(output, AsLogfmt()).Start()
+	var err *nilError
+
+	log.Log("err", err)
+
+	out.Close()
+	if strings.TrimSpace(output.String()) != `err="<nil>"` {
+		println(output.String())
+		t.Fail()
+	}
+}
+
+// Test of the typed nil error nested in the structured value. It
+// should be output as null.
+func TestError_TypedNilNested_JSON(t *testing.T) {
+	var err *nilError
+	var iface error = err
+
+	result := objectToJSON(struct {
+		Err   error
+		Cause interface{}
+	}{iface, iface})
+
+	if result != `{"Err":null,"Cause":null}` {
+		println(result)
+		t.Fail()
+	}
+}
@@ -33,6 +33,7 @@ OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 ॐ तारे तुत्तारे तुरे स्व */
 
 import (
+	"math"
 	"strconv"
 	"time"
 )
@@ -43,6 +44,26 @@ type Filter interface {
 	Check(string, string) bool
 }
 
+// TypedFilter could be realized by the custom filters that check the
+// original typed value of the pair (see Pair.Raw) instead of its string
+// representation. CheckTyped() called instead of Check() when the
+// original value is known. The string representation passed too so
+// the values of other types could be checked the same way as by
+// Check().
+type TypedFilter interface {
+	Filter
+	CheckTyped(key, val string, raw interface{}) bool
+}
+
+// checkFilter checks the pair with the typed value if the filter
+// supports it.
+func checkFilter(f Filter, p *Pair) bool {
+	if tf, ok := f.(TypedFilter); ok && p.Raw != nil {
+		return tf.CheckTyped(p.Key, p.Val, p.Raw)
+	}
+	return f.Check(p.Key, p.Val)
+}
+
 type keyFilter struct {
 }
 
@@ -80,6 +101,38 @@ func (f *int64RangeFilter) Check(key, val string) bool {
 	return intVal > f.From && intVal <= f.To
 }
 
+func (f *int64RangeFilter) CheckTyped(key, val string, raw interface{}) bool {
+	var intVal int64
+	switch v := raw.(type) {
+	case int:
+		intVal = int64(v)
+	case int8:
+		intVal = int64(v)
+	case int16:
+		intVal = int64(v)
+	case int32:
+		intVal = int64(v)
+	case int64:
+		intVal = v
+	case uint:
+		intVal = int64(v)
+	case uint8:
+		intVal = int64(v)
+	case uint16:
+		intVal = int64(v)
+	case uint32:
+		intVal = int64(v)
+	case uint64:
+		if v > math.MaxInt64 {
+			return false
+		}
+		intVal = int64(v)
+	default:
+		return f.Check(key, val)
+	}
+	return intVal > f.From && intVal <= f.To
+}
+
 type float64RangeFilter struct {
 	From, To float64
 }
@@ -95,6 +148,25 @@ func (f *float64RangeFilter) Check(key, val string) bool {
 	return floatVal > f.From && floatVal <= f.To
 }
 
+func (f *float64RangeFilter) CheckTyped(key, val string, raw interface{}) bool {
+	var floatVal float64
+	switch v := raw.(type) {
+	case float64:
+		floatVal = v
+	case float32:
+		floatVal = float64(v)
+	case int:
+		floatVal = float64(v)
+	case int64:
+		floatVal = float64(v)
+	case uint64:
+		floatVal = float64(v)
+	default:
+		return f.Check(key, val)
+	}
+	return floatVal > f.From && floatVal <= f.To
+}
+
 type timeRangeFilter struct {
 	From, To time.Time
 }
@@ -112,3 +184,11 @@ func (f *timeRangeFilter) Check(key, val string) bool {
 	}
 	return false
 }
+
+func (f *timeRangeFilter) CheckTyped(key, val string, raw interface{}) bool {
+	valTime, ok := raw.(time.Time)
+	if !ok {
+		return f.Check(key, val)
+	}
+	return f.From.Before(valTime) && f.To.After(valTime)
+}
@@ -0,0 +1,198 @@
+package kiwi
+
+// This file consists of the sink for Fluentd Forward protocol.
+
+/* Copyright (c) 2016-2020, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व */
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// FluentAckTimeout is the default time to wait the acknowledgement
+// of the chunk.
+var FluentAckTimeout = 5 * time.Second
+
+// fluentEventTimeExt is the extension type of EventTime in Forward
+// protocol.
+const fluentEventTimeExt = 0
+
+// FluentOptions defines the properties of Fluentd sink.
+type FluentOptions struct {
+	BatchOptions
+	// RequireAck enables at-least-once delivery. Each chunk
+	// should be acknowledged by the server otherwise the chunk is
+	// sent again with the same chunk id.
+	RequireAck bool
+	// AckTimeout is the time to wait the acknowledgement,
+	// FluentAckTimeout by default.
+	AckTimeout time.Duration
+}
+
+// SinkToFluent creates a new sink that sends the records to Fluentd
+// (or Fluent Bit) with Forward protocol over TCP. The records are
+// encoded with MessagePack and sent in batches in PackedForward mode
+// with the tag. The connection established lazily and reestablished
+// after the failures.
+//
+// As for SinkTo() the sink should be started explicitly.
+func SinkToFluent(addr, tag string, opts FluentOptions) *Sink {
+	var w = &fluentWriter{addr: addr, tag: tag, opts: opts}
+	if w.opts.AckTimeout <= 0 {
+		w.opts.AckTimeout = FluentAckTimeout
+	}
+	w.batch = newBatchWriter(opts.BatchOptions, w.forward)
+	var sink = SinkTo(w, AsMsgpack())
+	sink.closer = w
+	return sink
+}
+
+type fluentWriter struct {
+	addr  string
+	tag   string
+	opts  FluentOptions
+	batch *batchWriter
+	entry []byte
+
+	// The fields used by the flush of the batch.
+	sync.Mutex
+	conn    net.Conn
+	reader  *bufio.Reader
+	chunk   string
+	chunkOf *[]byte
+	msg     []byte
+}
+
+// Write gets the record formatted by MessagePack formatter and passes
+// it to the batch as the entry of Forward protocol: [time, record].
+func (w *fluentWriter) Write(p []byte) (int, error) {
+	if len(p) < binaryFrameHeader {
+		return 0, nil
+	}
+	var now = time.Now()
+	w.entry = appendMsgpackArrayHeader(w.entry[:0], 2)
+	w.entry = append(w.entry, 0xd7, fluentEventTimeExt)
+	w.entry = appendUint32(w.entry, uint32(now.Unix()))
+	w.entry = appendUint32(w.entry, uint32(now.Nanosecond()))
+	w.entry = append(w.entry, p[binaryFrameHeader:]...)
+	return w.batch.Write(w.entry)
+}
+
+// Close flushes the rest of the records and closes the connection.
+func (w *fluentWriter) Close() error {
+	w.batch.Close()
+	w.Lock()
+	defer w.Unlock()
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+	return nil
+}
+
+// forward sends the batch as PackedForward message and waits for the
+// acknowledgement if it required.
+func (w *fluentWriter) forward(batch [][]byte) error {
+	w.Lock()
+	defer w.Unlock()
+	// The same chunk id used for the retries of the same batch so
+	// the server could drop the duplicates.
+	if w.chunkOf != &batch[0] {
+		var id [16]byte
+		if _, err := rand.Read(id[:]); err != nil {
+			return err
+		}
+		w.chunk = base64.StdEncoding.EncodeToString(id[:])
+		w.chunkOf = &batch[0]
+	}
+	var size int
+	for _, entry := range batch {
+		size += len(entry)
+	}
+	w.msg = appendMsgpackArrayHeader(w.msg[:0], 3)
+	w.msg = appendMsgpackString(w.msg, w.tag)
+	w.msg = appendMsgpackBinHeader(w.msg, size)
+	for _, entry := range batch {
+		w.msg = append(w.msg, entry...)
+	}
+	if w.opts.RequireAck {
+		w.msg = appendMsgpackMapHeader(w.msg, 2)
+		w.msg = appendMsgpackString(w.msg, "size")
+		w.msg = appendMsgpackInt(w.msg, int64(len(batch)))
+		w.msg = appendMsgpackString(w.msg, "chunk")
+		w.msg = appendMsgpackString(w.msg, w.chunk)
+	} else {
+		w.msg = appendMsgpackMapHeader(w.msg, 1)
+		w.msg = appendMsgpackString(w.msg, "size")
+		w.msg = appendMsgpackInt(w.msg, int64(len(batch)))
+	}
+	if err := w.send(); err != nil {
+		if w.conn != nil {
+			w.conn.Close()
+			w.conn = nil
+		}
+		return retryableError{err}
+	}
+	w.chunkOf = nil
+	return nil
+}
+
+func (w *fluentWriter) send() error {
+	if w.conn == nil {
+		conn, err := net.DialTimeout("tcp", w.addr, NetDialTimeout)
+		if err != nil {
+			return err
+		}
+		w.conn = conn
+		w.reader = bufio.NewReader(conn)
+	}
+	if _, err := w.conn.Write(w.msg); err != nil {
+		return err
+	}
+	if !w.opts.RequireAck {
+		return nil
+	}
+	w.conn.SetReadDeadline(time.Now().Add(w.opts.AckTimeout))
+	resp, err := decodeMsgpack(w.reader)
+	w.conn.SetReadDeadline(time.Time{})
+	if err != nil {
+		return err
+	}
+	if m, ok := resp.(map[string]interface{}); ok && m["ack"] == w.chunk {
+		return nil
+	}
+	return fmt.Errorf("fluent: unexpected ack %v", resp)
+}
@@ -0,0 +1,131 @@
+package kiwi
+
+/*
+Copyright (c) 2016-2018, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व
+
+All tests consists of three parts:
+
+- arrange structures and initialize objects for use in tests
+- act on testing object
+- check and assert on results
+
+These parts separated by empty lines in each test function.
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+type forwardMessage struct {
+	tag     string
+	entries []interface{}
+	option  map[string]interface{}
+}
+
+// readForward reads PackedForward message with in-process server.
+func readForward(t *testing.T, r *bufio.Reader) forwardMessage {
+	v, err := decodeMsgpack(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := v.([]interface{})
+	var (
+		result  = forwardMessage{tag: msg[0].(string), option: msg[2].(map[string]interface{})}
+		entries = bufio.NewReader(bytes.NewReader(msg[1].([]byte)))
+	)
+	for {
+		e, err := decodeMsgpack(entries)
+		if err != nil {
+			break
+		}
+		result.entries = append(result.entries, e)
+	}
+	return result
+}
+
+// Test of Fluentd sink with acknowledgements. The chunk that is not
+// acknowledged should be sent again with the same chunk id.
+func TestFluent_ForwardAck(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	received := make(chan forwardMessage, 2)
+	go func() {
+		for i := 0; i < 2; i++ {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			msg := readForward(t, bufio.NewReader(conn))
+			received <- msg
+			// The first message is not acknowledged.
+			if i > 0 {
+				ack := appendMsgpackMapHeader(nil, 1)
+				ack = appendMsgpackString(ack, "ack")
+				ack = appendMsgpackString(ack, msg.option["chunk"].(string))
+				conn.Write(ack)
+			}
+			time.Sleep(10 * time.Millisecond)
+			conn.Close()
+		}
+	}()
+	log := New()
+	out := SinkToFluent(l.Addr().String(), "app.test", FluentOptions{
+		BatchOptions: BatchOptions{Interval: time.Hour, Backoff: time.Millisecond},
+		RequireAck:   true,
+		AckTimeout:   100 * time.Millisecond,
+	}).Start()
+
+	log.Log("n", 1, "msg", "first")
+	log.Log("n", 2)
+
+	out.Close()
+	first, second := <-received, <-received
+	if first.tag != "app.test" || len(first.entries) != 2 || first.option["size"] != int64(2) {
+		t.Fatalf("unexpected message %+v", first)
+	}
+	if first.option["chunk"] == nil || first.option["chunk"] != second.option["chunk"] {
+		t.Errorf("unexpected chunk ids %v and %v", first.option, second.option)
+	}
+	entry := second.entries[0].([]interface{})
+	if ts, ok := entry[0].(msgpackExt); !ok || ts.Type != fluentEventTimeExt || len(ts.Data) != 8 {
+		t.Errorf("unexpected time %v", entry[0])
+	}
+	if rec := entry[1].(map[string]interface{}); rec["n"] != int64(1) || rec["msg"] != "first" {
+		t.Errorf("unexpected record %v", rec)
+	}
+}
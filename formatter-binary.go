@@ -0,0 +1,150 @@
+package kiwi
+
+// This file consists of the formatters for binary formats: MessagePack and CBOR.
+
+/* Copyright (c) 2016-2020, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व */
+
+import (
+	"encoding/binary"
+	"strconv"
+	"time"
+)
+
+// binaryEncoding defines the encoding of the values for binary
+// formatters.
+type binaryEncoding struct {
+	mapHeader func([]byte, int) []byte
+	str       func([]byte, string) []byte
+	integer   func([]byte, int64) []byte
+	unsigned  func([]byte, uint64) []byte
+	float     func([]byte, float64) []byte
+	boolean   func([]byte, bool) []byte
+	time      func([]byte, time.Time) []byte
+}
+
+var (
+	msgpackEncoding = binaryEncoding{
+		mapHeader: appendMsgpackMapHeader,
+		str:       appendMsgpackString,
+		integer:   appendMsgpackInt,
+		unsigned:  appendMsgpackUint,
+		float:     appendMsgpackFloat,
+		boolean:   appendMsgpackBool,
+		time:      appendMsgpackTime,
+	}
+	cborEncoding = binaryEncoding{
+		mapHeader: appendCBORMapHeader,
+		str:       appendCBORString,
+		integer:   appendCBORInt,
+		unsigned:  appendCBORUint,
+		float:     appendCBORFloat,
+		boolean:   appendCBORBool,
+		time:      appendCBORTime,
+	}
+)
+
+// binaryFrameHeader is the size of the length prefix of each record.
+const binaryFrameHeader = 4
+
+type formatBinary struct {
+	enc   *binaryEncoding
+	line  []byte
+	body  []byte
+	count int
+}
+
+// AsMsgpack says that a sink uses MessagePack format for records
+// output. Each record encoded as a map with the values typed
+// accordingly with their type hints: integers, floats, booleans and
+// timestamps (the original values or parsed with TimeLayout) encoded with native types. The
+// record prefixed with its length as 4 bytes big-endian integer so
+// the stream could be read record by record.
+func AsMsgpack() *formatBinary {
+	return &formatBinary{enc: &msgpackEncoding}
+}
+
+// AsCBOR says that a sink uses CBOR (RFC-7049) format for records
+// output. The record encoded and framed the same way as for
+// AsMsgpack(). Timestamps encoded as epoch based date/time (tag 1).
+func AsCBOR() *formatBinary {
+	return &formatBinary{enc: &cborEncoding}
+}
+
+func (f *formatBinary) Begin() {
+	f.body = f.body[:0]
+	f.count = 0
+}
+
+func (f *formatBinary) Pair(key, val string, valType int) {
+	f.TypedPair(key, val, nil, valType)
+}
+
+func (f *formatBinary) TypedPair(key, val string, raw interface{}, valType int) {
+	f.count++
+	f.body = f.enc.str(f.body, key)
+	f.body = appendBinaryValue(f.enc, f.body, val, raw, valType)
+}
+
+func (f *formatBinary) Finish() []byte {
+	f.line = append(f.line[:0], 0, 0, 0, 0)
+	f.line = f.enc.mapHeader(f.line, f.count)
+	f.line = append(f.line, f.body...)
+	binary.BigEndian.PutUint32(f.line, uint32(len(f.line)-binaryFrameHeader))
+	return f.line
+}
+
+// appendBinaryValue encodes the value with the native type selected
+// by the type hint. It falls back to the string if the value could
+// not be parsed.
+func appendBinaryValue(enc *binaryEncoding, b []byte, val string, raw interface{}, valType int) []byte {
+	switch valType {
+	case BooleanVal:
+		if v, err := strconv.ParseBool(val); err == nil {
+			return enc.boolean(b, v)
+		}
+	case IntegerVal:
+		if v, err := strconv.ParseInt(val, 10, 64); err == nil {
+			return enc.integer(b, v)
+		}
+		if v, err := strconv.ParseUint(val, 10, 64); err == nil {
+			return enc.unsigned(b, v)
+		}
+	case FloatVal:
+		if v, err := strconv.ParseFloat(val, 64); err == nil {
+			return enc.float(b, v)
+		}
+	case TimeVal:
+		if v, ok := pairTime(val, raw); ok {
+			return enc.time(b, v)
+		}
+	}
+	return enc.str(b, val)
+}
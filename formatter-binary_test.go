@@ -0,0 +1,133 @@
+package kiwi
+
+/*
+Copyright (c) 2016-2018, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व
+
+All tests consists of three parts:
+
+- arrange structures and initialize objects for use in tests
+- act on testing object
+- check and assert on results
+
+These parts separated by empty lines in each test function.
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+)
+
+// Test of MessagePack formatter. Each record should be framed with
+// its length and the values should have native types.
+func TestFormatter_Msgpack(t *testing.T) {
+	output := bytes.NewBufferString("")
+	log := New()
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	out := SinkTo(output, AsMsgpack()).Start()
+
+	log.Log("s", "str", "i", -300, "u", uint64(1<<63), "f", 0.5, "b", true, "t", at)
+	log.Log("second", 2)
+
+	out.Close()
+	var records []map[string]interface{}
+	for output.Len() > 0 {
+		size := binary.BigEndian.Uint32(output.Next(4))
+		r := bufio.NewReader(bytes.NewReader(output.Next(int(size))))
+		v, err := decodeMsgpack(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = r.ReadByte(); err != io.EOF {
+			t.Fatal("unexpected data after the record")
+		}
+		records = append(records, v.(map[string]interface{}))
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	rec := records[0]
+	if rec["s"] != "str" || rec["i"] != int64(-300) || rec["u"] != uint64(1<<63) || rec["f"] != 0.5 || rec["b"] != true {
+		t.Errorf("unexpected record %v", rec)
+	}
+	if tm, ok := rec["t"].(time.Time); !ok || !tm.Equal(at) {
+		t.Errorf("unexpected time %v", rec["t"])
+	}
+	if records[1]["second"] != int64(2) {
+		t.Errorf("unexpected record %v", records[1])
+	}
+}
+
+// Test of CBOR formatter. Check the exact encoding of the record.
+func TestFormatter_CBOR(t *testing.T) {
+	f := AsCBOR()
+
+	f.Begin()
+	f.Pair("a", "-2", IntegerVal)
+	f.Pair("b", "false", BooleanVal)
+	f.Pair("t", time.Unix(1500000000, 0).Format(TimeLayout), TimeVal)
+	f.Pair("s", "x", StringVal)
+	result := f.Finish()
+
+	expected := []byte{0, 0, 0, 19,
+		0xa4,
+		0x61, 'a', 0x21,
+		0x61, 'b', 0xf4,
+		0x61, 't', 0xc1, 0x1a, 0x59, 0x68, 0x2f, 0x00,
+		0x61, 's', 0x61, 'x'}
+	if !bytes.Equal(result, expected) {
+		t.Errorf("unexpected encoding % x", result)
+	}
+}
+
+// Test of MessagePack formatter with the time layout of the sink. The
+// time should be encoded natively from the original value.
+func TestFormatter_MsgpackSinkTimeLayout(t *testing.T) {
+	output := bytes.NewBufferString("")
+	log := New()
+	at := time.Date(2020, 1, 2, 3, 4, 5, 6000, time.UTC)
+	out := SinkTo(output, AsMsgpack()).TimeLayout(time.Kitchen).Start()
+
+	log.Log("t", at)
+
+	out.Close()
+	size := binary.BigEndian.Uint32(output.Next(4))
+	v, err := decodeMsgpack(bufio.NewReader(bytes.NewReader(output.Next(int(size)))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tm, ok := v.(map[string]interface{})["t"].(time.Time); !ok || !tm.Equal(at) {
+		t.Errorf("unexpected record %v", v)
+	}
+}
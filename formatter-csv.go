@@ -0,0 +1,124 @@
+package kiwi
+
+// This file consists of the formatter for CSV and TSV output.
+
+/* Copyright (c) 2016-2020, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व */
+
+import (
+	"bytes"
+	"encoding/csv"
+)
+
+// DefaultRestColumn is the name of the column that gathers the pairs
+// that not found in the list of the columns of CSV formatter.
+var DefaultRestColumn = "rest"
+
+type formatCSV struct {
+	columns    []string
+	index      map[string]int
+	restName   string
+	header     bool
+	headerDone bool
+	row        []string
+	rest       *formatLogfmt
+	line       *bytes.Buffer
+	w          *csv.Writer
+}
+
+// AsCSV says that a sink uses CSV (RFC-4180) format for records
+// output. Each record written as a single row with the values in the
+// order of the columns. The values for the missed keys left
+// empty. The pairs with the keys not listed in the columns are
+// written in logfmt format to the last catch-all column (see
+// RestColumn()).
+func AsCSV(columns ...string) *formatCSV {
+	var f = &formatCSV{
+		columns:  columns,
+		index:    make(map[string]int, len(columns)),
+		restName: DefaultRestColumn,
+		row:      make([]string, len(columns)+1),
+		rest:     AsLogfmt(),
+		line:     bytes.NewBuffer(make([]byte, 256)),
+	}
+	for i, c := range columns {
+		f.index[c] = i
+	}
+	f.w = csv.NewWriter(f.line)
+	return f
+}
+
+// AsTSV says that a sink uses tab separated values for records
+// output. It is the same as AsCSV but with tab as the delimiter.
+func AsTSV(columns ...string) *formatCSV {
+	var f = AsCSV(columns...)
+	f.w.Comma = '\t'
+	return f
+}
+
+// WithHeader says that the header row with names of the columns
+// should be written before the first record.
+func (f *formatCSV) WithHeader() *formatCSV {
+	f.header = true
+	return f
+}
+
+// RestColumn sets the name of catch-all column for the header.
+func (f *formatCSV) RestColumn(name string) *formatCSV {
+	f.restName = name
+	return f
+}
+
+func (f *formatCSV) Begin() {
+	f.line.Reset()
+	for i := range f.row {
+		f.row[i] = ""
+	}
+	f.rest.Begin()
+	if f.header && !f.headerDone {
+		f.w.Write(append(f.columns[:len(f.columns):len(f.columns)], f.restName))
+		f.headerDone = true
+	}
+}
+
+func (f *formatCSV) Pair(key, val string, valType int) {
+	if i, ok := f.index[key]; ok {
+		f.row[i] = val
+		return
+	}
+	f.rest.Pair(key, val, valType)
+}
+
+func (f *formatCSV) Finish() []byte {
+	f.row[len(f.columns)] = string(bytes.TrimRight(f.rest.line.Bytes(), " "))
+	f.w.Write(f.row)
+	f.w.Flush()
+	return f.line.Bytes()
+}
@@ -0,0 +1,245 @@
+package kiwi
+
+// This file consists of the formatter for OpenTelemetry log data model.
+
+/* Copyright (c) 2016-2020, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व */
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default keys used by OTLP formatter for the timestamp and tracing
+// context of the record.
+var (
+	OTLPTimeKey  = "at"
+	OTLPTraceKey = "trace_id"
+	OTLPSpanKey  = "span_id"
+)
+
+// OpenTelemetry severity numbers for syslog severities.
+var otlpSeverities = [...]int{
+	sevEmergency: 21, // FATAL
+	sevAlert:     19, // ERROR3
+	sevCritical:  18, // ERROR2
+	sevError:     17, // ERROR
+	sevWarning:   13, // WARN
+	sevNotice:    10, // INFO2
+	sevInfo:      9,  // INFO
+	sevDebug:     5,  // DEBUG
+}
+
+type formatOTLP struct {
+	line     *bytes.Buffer
+	attrs    *bytes.Buffer
+	timeKey  string
+	traceKey string
+	spanKey  string
+	body     string
+	hasBody  bool
+	level    string
+	at       time.Time
+	traceID  string
+	spanID   string
+}
+
+// AsOTLP says that a sink uses JSON encoding of OpenTelemetry log
+// record (OTLP log data model) for records output. Each record output
+// as a single line. The value of MessageKey becomes the body of the
+// log record and the value of LevelKey becomes severity text with the
+// severity number mapped from it. The value of the time key (see
+// OTLPTimeKey) becomes "timeUnixNano". The
+// trace and span ids passed as hex strings in the keys defined by
+// OTLPTraceKey and OTLPSpanKey. All other pairs became attributes
+// typed by hints of their values.
+func AsOTLP() *formatOTLP {
+	return &formatOTLP{
+		line:     bytes.NewBuffer(make([]byte, 512)),
+		attrs:    bytes.NewBuffer(make([]byte, 512)),
+		timeKey:  OTLPTimeKey,
+		traceKey: OTLPTraceKey,
+		spanKey:  OTLPSpanKey,
+	}
+}
+
+// TimeKey sets the key of the record timestamp for the formatter.
+func (f *formatOTLP) TimeKey(key string) *formatOTLP {
+	f.timeKey = key
+	return f
+}
+
+// TraceKeys sets the keys of trace and span ids for the formatter.
+func (f *formatOTLP) TraceKeys(traceKey, spanKey string) *formatOTLP {
+	f.traceKey = traceKey
+	f.spanKey = spanKey
+	return f
+}
+
+func (f *formatOTLP) Begin() {
+	f.line.Reset()
+	f.attrs.Reset()
+	f.body = ""
+	f.hasBody = false
+	f.level = ""
+	f.at = time.Time{}
+	f.traceID = ""
+	f.spanID = ""
+}
+
+func (f *formatOTLP) Pair(key, val string, valType int) {
+	f.TypedPair(key, val, nil, valType)
+}
+
+func (f *formatOTLP) TypedPair(key, val string, raw interface{}, valType int) {
+	switch key {
+	case MessageKey:
+		f.body = val
+		f.hasBody = true
+		return
+	case LevelKey:
+		f.level = val
+		return
+	case f.timeKey:
+		if t, ok := pairTime(val, raw); ok {
+			f.at = t
+			return
+		}
+	case f.traceKey:
+		if isHexID(val, 16) {
+			f.traceID = strings.ToLower(val)
+			return
+		}
+	case f.spanKey:
+		if isHexID(val, 8) {
+			f.spanID = strings.ToLower(val)
+			return
+		}
+	}
+	if f.attrs.Len() > 0 {
+		f.attrs.WriteRune(',')
+	}
+	f.attrs.WriteString(`{"key":`)
+	writeJSONString(f.attrs, key)
+	f.attrs.WriteString(`,"value":`)
+	writeOTLPValue(f.attrs, val, valType)
+	f.attrs.WriteRune('}')
+}
+
+func (f *formatOTLP) Finish() []byte {
+	var observed = time.Now()
+	if f.at.IsZero() {
+		f.at = observed
+	}
+	f.line.WriteString(`{"timeUnixNano":"`)
+	f.line.WriteString(strconv.FormatInt(f.at.UnixNano(), 10))
+	f.line.WriteString(`","observedTimeUnixNano":"`)
+	f.line.WriteString(strconv.FormatInt(observed.UnixNano(), 10))
+	f.line.WriteRune('"')
+	if f.level != "" {
+		f.line.WriteString(`,"severityNumber":`)
+		f.line.WriteString(strconv.Itoa(otlpSeverity(f.level)))
+		f.line.WriteString(`,"severityText":`)
+		writeJSONString(f.line, f.level)
+	}
+	if f.hasBody {
+		f.line.WriteString(`,"body":`)
+		writeOTLPValue(f.line, f.body, StringVal)
+	}
+	f.line.WriteString(`,"attributes":[`)
+	f.line.Write(f.attrs.Bytes())
+	f.line.WriteRune(']')
+	if f.traceID != "" {
+		f.line.WriteString(`,"traceId":"`)
+		f.line.WriteString(f.traceID)
+		f.line.WriteRune('"')
+	}
+	if f.spanID != "" {
+		f.line.WriteString(`,"spanId":"`)
+		f.line.WriteString(f.spanID)
+		f.line.WriteRune('"')
+	}
+	f.line.WriteString("}\n")
+	return f.line.Bytes()
+}
+
+// writeOTLPValue writes the value as AnyValue of OTLP JSON encoding
+// accordingly with the type hint of the value.
+func writeOTLPValue(buf *bytes.Buffer, val string, valType int) {
+	switch valType {
+	case BooleanVal:
+		if val == "true" || val == "false" {
+			buf.WriteString(`{"boolValue":`)
+			buf.WriteString(val)
+			buf.WriteRune('}')
+			return
+		}
+	case IntegerVal:
+		// 64-bit integers are encoded as strings in OTLP JSON.
+		if _, err := strconv.ParseInt(val, 10, 64); err == nil {
+			buf.WriteString(`{"intValue":"`)
+			buf.WriteString(val)
+			buf.WriteString(`"}`)
+			return
+		}
+	case FloatVal:
+		if v, err := strconv.ParseFloat(val, 64); err == nil && !math.IsInf(v, 0) && !math.IsNaN(v) {
+			buf.WriteString(`{"doubleValue":`)
+			buf.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+			buf.WriteRune('}')
+			return
+		}
+	}
+	buf.WriteString(`{"stringValue":`)
+	writeJSONString(buf, val)
+	buf.WriteRune('}')
+}
+
+func otlpSeverity(level string) int {
+	switch strings.ToLower(level) {
+	case "trace":
+		return 1
+	case "fatal":
+		return 21
+	}
+	return otlpSeverities[syslogSeverity(level)]
+}
+
+func isHexID(s string, size int) bool {
+	if len(s) != size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,125 @@
+package kiwi
+
+/*
+Copyright (c) 2016-2018, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व
+
+All tests consists of three parts:
+
+- arrange structures and initialize objects for use in tests
+- act on testing object
+- check and assert on results
+
+These parts separated by empty lines in each test function.
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// Test of OTLP formatter. Pairs should be mapped to the fields of log
+// record and to typed attributes.
+func TestFormatter_OTLP(t *testing.T) {
+	f := AsOTLP()
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	var rec struct {
+		TimeUnixNano   string
+		SeverityNumber int
+		SeverityText   string
+		Body           map[string]interface{}
+		TraceID        string
+		SpanID         string
+		Attributes     []struct {
+			Key   string
+			Value map[string]interface{}
+		}
+	}
+
+	f.Begin()
+	f.Pair(MessageKey, "hello", StringVal)
+	f.Pair(LevelKey, "warning", StringVal)
+	f.Pair(OTLPTimeKey, at.Format(TimeLayout), TimeVal)
+	f.Pair(OTLPTraceKey, "0af7651916cd43dd8448eb211c80319c", StringVal)
+	f.Pair(OTLPSpanKey, "b7ad6b7169203331", StringVal)
+	f.Pair("count", "42", IntegerVal)
+	f.Pair("ratio", "5e-01", FloatVal)
+	f.Pair("ok", "true", BooleanVal)
+	f.Pair("user", "alice", StringVal)
+	err := json.Unmarshal(f.Finish(), &rec)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.TimeUnixNano != "1577934245000000000" || rec.SeverityNumber != 13 || rec.SeverityText != "warning" {
+		t.Errorf("unexpected record %+v", rec)
+	}
+	if rec.Body["stringValue"] != "hello" || rec.TraceID != "0af7651916cd43dd8448eb211c80319c" || rec.SpanID != "b7ad6b7169203331" {
+		t.Errorf("unexpected record %+v", rec)
+	}
+	expected := []map[string]interface{}{
+		{"intValue": "42"},
+		{"doubleValue": 0.5},
+		{"boolValue": true},
+		{"stringValue": "alice"},
+	}
+	if len(rec.Attributes) != len(expected) {
+		t.Fatalf("unexpected attributes %+v", rec.Attributes)
+	}
+	for i, a := range rec.Attributes {
+		for k, v := range expected[i] {
+			if a.Value[k] != v {
+				t.Errorf("unexpected attribute %s: %v", a.Key, a.Value)
+			}
+		}
+	}
+}
+
+// Test of OTLP formatter with the time layout of the sink. The time
+// of the record should be taken from the original value.
+func TestFormatter_OTLPSinkTimeLayout(t *testing.T) {
+	output := bytes.NewBufferString("")
+	log := New()
+	at := time.Date(2020, 1, 2, 3, 4, 5, 6000, time.UTC)
+	out := SinkTo(output, AsOTLP()).TimeLayout(time.Kitchen).Start()
+	var rec struct{ TimeUnixNano string }
+
+	log.Log(OTLPTimeKey, at)
+
+	out.Close()
+	if err := json.Unmarshal(output.Bytes(), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec.TimeUnixNano != "1577934245000006000" {
+		t.Errorf("unexpected record %+v", rec)
+	}
+}
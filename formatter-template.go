@@ -0,0 +1,178 @@
+package kiwi
+
+// This file consists of the formatter based on text/template package.
+
+/* Copyright (c) 2016-2020, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व */
+
+import (
+	"bytes"
+	"text/template"
+	"text/template/parse"
+)
+
+type formatTemplate struct {
+	tpl   *template.Template
+	used  map[string]bool
+	keys  []string
+	vals  map[string]string
+	types map[string]int
+	line  *bytes.Buffer
+	rest  *formatLogfmt
+}
+
+// AsTemplate says that a sink uses text/template for records output.
+// The pairs of the record passed to the template as a map so the
+// values could be referred by their keys: `{{.message}}` or
+// `{{index . "kiwi-error"}}` for the keys that are not valid Go
+// identifiers. Missed keys rendered as empty strings. The template
+// function `rest` renders the pairs
+// not referred by the template in logfmt format:
+//
+//	kiwi.AsTemplate(`[{{.at}}] {{.level}} {{.message}} {{rest}}`)
+//
+// It returns an error if the template could not be parsed.
+func AsTemplate(tpl string) (*formatTemplate, error) {
+	var (
+		f = &formatTemplate{
+			used:  make(map[string]bool),
+			vals:  make(map[string]string),
+			types: make(map[string]int),
+			line:  bytes.NewBuffer(make([]byte, 256)),
+			rest:  AsLogfmt(),
+		}
+		err error
+	)
+	f.tpl, err = template.New("kiwi").Option("missingkey=zero").Funcs(template.FuncMap{"rest": f.renderRest}).Parse(tpl)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range f.tpl.Templates() {
+		if t.Tree != nil {
+			f.collectKeys(t.Tree.Root)
+		}
+	}
+	return f, nil
+}
+
+func (f *formatTemplate) Begin() {
+	f.line.Reset()
+	f.keys = f.keys[:0]
+	for k := range f.vals {
+		delete(f.vals, k)
+		delete(f.types, k)
+	}
+}
+
+func (f *formatTemplate) Pair(key, val string, valType int) {
+	if _, ok := f.vals[key]; !ok {
+		f.keys = append(f.keys, key)
+	}
+	f.vals[key] = val
+	f.types[key] = valType
+}
+
+func (f *formatTemplate) Finish() []byte {
+	if err := f.tpl.Execute(f.line, f.vals); err != nil {
+		f.line.Reset()
+		f.rest.Begin()
+		f.rest.Pair(ErrorKey, err.Error(), StringVal)
+		f.line.Write(bytes.TrimRight(f.rest.line.Bytes(), " "))
+	}
+	if b := f.line.Bytes(); len(b) == 0 || b[len(b)-1] != '\n' {
+		f.line.WriteRune('\n')
+	}
+	return f.line.Bytes()
+}
+
+// renderRest outputs the pairs of the current record that are not
+// referred by the template.
+func (f *formatTemplate) renderRest() string {
+	f.rest.Begin()
+	for _, key := range f.keys {
+		if f.used[key] {
+			continue
+		}
+		f.rest.Pair(key, f.vals[key], f.types[key])
+	}
+	return string(bytes.TrimRight(f.rest.line.Bytes(), " "))
+}
+
+// collectKeys walks the parsed template and remembers the keys of the
+// record referred as the fields of the dot or with index function.
+func (f *formatTemplate) collectKeys(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			f.collectKeys(c)
+		}
+	case *parse.ActionNode:
+		f.collectKeys(n.Pipe)
+	case *parse.IfNode:
+		f.collectBranch(&n.BranchNode)
+	case *parse.RangeNode:
+		f.collectBranch(&n.BranchNode)
+	case *parse.WithNode:
+		f.collectBranch(&n.BranchNode)
+	case *parse.TemplateNode:
+		f.collectKeys(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Cmds {
+			f.collectKeys(c)
+		}
+	case *parse.CommandNode:
+		if len(n.Args) == 3 {
+			if fn, ok := n.Args[0].(*parse.IdentifierNode); ok && fn.Ident == "index" {
+				if s, ok := n.Args[2].(*parse.StringNode); ok {
+					f.used[s.Text] = true
+				}
+			}
+		}
+		for _, c := range n.Args {
+			f.collectKeys(c)
+		}
+	case *parse.FieldNode:
+		if len(n.Ident) > 0 {
+			f.used[n.Ident[0]] = true
+		}
+	}
+}
+
+func (f *formatTemplate) collectBranch(n *parse.BranchNode) {
+	f.collectKeys(n.Pipe)
+	f.collectKeys(n.List)
+	f.collectKeys(n.ElseList)
+}
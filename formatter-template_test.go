@@ -0,0 +1,97 @@
+package kiwi
+
+/*
+Copyright (c) 2016-2018, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व
+
+All tests consists of three parts:
+
+- arrange structures and initialize objects for use in tests
+- act on testing object
+- check and assert on results
+
+These parts separated by empty lines in each test function.
+*/
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// Test of the template formatter. The pairs not referred by the
+// template should be rendered by rest function in logfmt.
+func TestFormatter_Template(t *testing.T) {
+	output := bytes.NewBufferString("")
+	log := New()
+	tpl, err := AsTemplate(`[{{.at}}] {{.level}} {{.message}} {{rest}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := SinkTo(output, tpl).Start()
+
+	log.Log("level", "info", "at", "2020-01-01", "user", "alice", "count", 3, "message", "hello")
+
+	out.Close()
+	if strings.TrimSpace(output.String()) != `[2020-01-01] info hello user="alice" count=3` {
+		println(output.String())
+		t.Fail()
+	}
+}
+
+// Test of the template formatter with a key that is not valid Go
+// identifier. The key referred with index function should not be
+// rendered by rest.
+func TestFormatter_TemplateIndex(t *testing.T) {
+	output := bytes.NewBufferString("")
+	log := New()
+	tpl, err := AsTemplate(`{{index . "the-key"}}|{{rest}}|{{.missed}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := SinkTo(output, tpl).Start()
+
+	log.Log("the-key", "value", "other", 1)
+
+	out.Close()
+	if strings.TrimSpace(output.String()) != `value|other=1|` {
+		println(output.String())
+		t.Fail()
+	}
+}
+
+// Test of the invalid template. It should return an error.
+func TestFormatter_TemplateInvalid(t *testing.T) {
+	_, err := AsTemplate(`{{.message`)
+
+	if err == nil {
+		t.Fail()
+	}
+}
@@ -34,8 +34,10 @@ OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 
 import (
 	"bytes"
+	"encoding/json"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Formatter represents format of the output.
@@ -53,6 +55,26 @@ type Formatter interface {
 	Finish() []byte
 }
 
+// TypedFormatter could be realized by the formatters that output the
+// values with their native types. The sink calls TypedPair() instead
+// of Pair() for them with the original typed value of the pair (see
+// Pair.Raw) or nil if it is unknown. The value string is formatted
+// accordingly with the float format and the time layout of the sink.
+type TypedFormatter interface {
+	Formatter
+	TypedPair(key, value string, raw interface{}, valueType int)
+}
+
+// pairTime returns the time of the pair. The original value used if
+// it known else the string parsed with TimeLayout.
+func pairTime(val string, raw interface{}) (time.Time, bool) {
+	if t, ok := raw.(time.Time); ok {
+		return t, true
+	}
+	t, err := time.Parse(TimeLayout, val)
+	return t, err == nil
+}
+
 type formatLogfmt struct {
 	line *bytes.Buffer
 }
@@ -67,6 +89,27 @@ func (f *formatLogfmt) Begin() {
 }
 
 func (f *formatLogfmt) Pair(key, val string, valType int) {
+	if valType == ErrorVal || valType == ObjectVal {
+		// The structured values flattened to the pairs with
+		// dotted keys. The message of the error displayed
+		// with the key as is. The pairs already written are
+		// discarded if the value could not be flattened.
+		var (
+			msgKey = key + ".message"
+			mark   = f.line.Len()
+		)
+		err := flattenJSON(key, val, func(k, v string, t int) {
+			if valType == ErrorVal && k == msgKey {
+				k = key
+			}
+			f.Pair(k, v, t)
+		})
+		if err == nil {
+			return
+		}
+		f.line.Truncate(mark)
+		valType = StringVal
+	}
 	// TODO allow multiline values output?
 	// TODO extend check for all non printable chars, so it need just check for each byte>space
 	if strings.ContainsAny(key, " \n\r\t") {
@@ -105,15 +148,21 @@ func (f *formatJSON) Begin() {
 }
 
 func (f *formatJSON) Pair(key, val string, valType int) {
+	// The separator written before all the pairs except the
+	// first one so the record stays valid JSON.
+	if f.line.Len() > 1 {
+		f.line.WriteString(", ")
+	}
 	f.line.WriteString(strconv.Quote(key))
 	f.line.WriteRune(':')
 	switch valType {
 	case StringVal, TimeVal, CustomQuoted:
 		f.line.WriteString(strconv.Quote(val))
 	default:
+		// The numbers, booleans, the errors and the objects
+		// (they are JSON already) are written as is.
 		f.line.WriteString(val)
 	}
-	f.line.WriteString(", ")
 }
 
 func (f *formatJSON) Finish() []byte {
@@ -121,3 +170,90 @@ func (f *formatJSON) Finish() []byte {
 	f.line.WriteRune('\n')
 	return f.line.Bytes()
 }
+
+const hexDigits = "0123456789abcdef"
+
+// writeJSONString writes the string quoted and escaped in accordance
+// with RFC-7159. Unlike strconv.Quote() it never produces \x escapes
+// that are not valid in JSON.
+func writeJSONString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case r == '\n':
+			buf.WriteString(`\n`)
+		case r == '\r':
+			buf.WriteString(`\r`)
+		case r == '\t':
+			buf.WriteString(`\t`)
+		case r < 0x20:
+			buf.WriteString(`\u00`)
+			buf.WriteByte(hexDigits[r>>4])
+			buf.WriteByte(hexDigits[r&0xf])
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// flattenJSON walks JSON value and calls the function for each scalar
+// value with the key built from the keys of the objects and the
+// indexes of the arrays joined with the dots. Empty objects and
+// arrays passed as is.
+func flattenJSON(key, data string, fn func(key, val string, valType int)) error {
+	var dec = json.NewDecoder(strings.NewReader(data))
+	dec.UseNumber()
+	return flattenJSONValue(dec, key, fn)
+}
+
+func flattenJSONValue(dec *json.Decoder, key string, fn func(string, string, int)) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch v := tok.(type) {
+	case json.Delim:
+		var i int
+		for ; dec.More(); i++ {
+			var sub string
+			if v == '{' {
+				if tok, err = dec.Token(); err != nil {
+					return err
+				}
+				sub = key + "." + tok.(string)
+			} else {
+				sub = key + "." + strconv.Itoa(i)
+			}
+			if err = flattenJSONValue(dec, sub, fn); err != nil {
+				return err
+			}
+		}
+		if _, err = dec.Token(); err != nil {
+			return err
+		}
+		if i == 0 {
+			if v == '{' {
+				fn(key, "{}", CustomUnquoted)
+			} else {
+				fn(key, "[]", CustomUnquoted)
+			}
+		}
+	case string:
+		fn(key, v, StringVal)
+	case json.Number:
+		if _, err = v.Int64(); err == nil {
+			fn(key, v.String(), IntegerVal)
+		} else {
+			fn(key, v.String(), FloatVal)
+		}
+	case bool:
+		fn(key, strconv.FormatBool(v), BooleanVal)
+	case nil:
+		fn(key, "null", CustomUnquoted)
+	}
+	return nil
+}
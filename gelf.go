@@ -0,0 +1,242 @@
+package kiwi
+
+// This file consists of GELF formatter and UDP writer for Graylog.
+
+/* Copyright (c) 2016-2020, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व */
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/rand"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Compression methods for GELF messages sent over UDP.
+const (
+	GELFNoCompression = iota
+	GELFGzip
+	GELFZlib
+)
+
+const (
+	gelfChunkHeaderLen = 12
+	gelfMaxChunks      = 128
+)
+
+// GELFChunkSize is the default maximum size of UDP datagram sent by
+// GELF writer. Messages that exceed it are chunked.
+var GELFChunkSize = 1420
+
+// ErrGELFTooLarge returned by GELF writer for the messages that
+// could not be sent in 128 chunks.
+var ErrGELFTooLarge = errors.New("gelf message too large")
+
+type formatGELF struct {
+	line    *bytes.Buffer
+	host    string
+	message string
+	level   int
+}
+
+// AsGELF says that a sink uses GELF 1.1 format (Graylog Extended Log
+// Format) for records output. The value of MessageKey becomes
+// "short_message" and the value of LevelKey mapped to syslog severity
+// of "level" field. All other pairs are passed as additional fields
+// with "_" prefix ("id" becomes "__id" because "_id" is reserved). If
+// the host is empty then the hostname of the system used.
+func AsGELF(host string) *formatGELF {
+	if host == "" {
+		host, _ = os.Hostname()
+	}
+	return &formatGELF{line: bytes.NewBuffer(make([]byte, 512)), host: host}
+}
+
+func (f *formatGELF) Begin() {
+	f.line.Reset()
+	f.line.WriteRune('{')
+	f.message = ""
+	f.level = sevInfo
+}
+
+func (f *formatGELF) Pair(key, val string, valType int) {
+	switch key {
+	case MessageKey:
+		f.message = val
+		return
+	case LevelKey:
+		f.level = syslogSeverity(val)
+		return
+	}
+	f.line.WriteString(`"_`)
+	// The field "_id" is reserved by GELF so the key "id" gets
+	// one more underscore.
+	if key == "id" {
+		f.line.WriteByte('_')
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '-' || c == '_' {
+			f.line.WriteByte(c)
+		} else {
+			f.line.WriteByte('_')
+		}
+	}
+	f.line.WriteString(`":`)
+	switch valType {
+	case IntegerVal, FloatVal:
+		f.line.WriteString(val)
+	default:
+		writeJSONString(f.line, val)
+	}
+	f.line.WriteRune(',')
+}
+
+func (f *formatGELF) Finish() []byte {
+	if f.message == "" {
+		f.message = "-"
+	}
+	f.line.WriteString(`"version":"1.1","host":`)
+	writeJSONString(f.line, f.host)
+	f.line.WriteString(`,"short_message":`)
+	writeJSONString(f.line, f.message)
+	f.line.WriteString(`,"timestamp":`)
+	f.line.WriteString(strconv.FormatFloat(float64(time.Now().UnixNano()/1e6)/1e3, 'f', 3, 64))
+	f.line.WriteString(`,"level":`)
+	f.line.WriteString(strconv.Itoa(f.level))
+	f.line.WriteString("}\n")
+	return f.line.Bytes()
+}
+
+// GELFWriter sends GELF messages to Graylog over UDP. Messages that
+// exceed the chunk size are split in accordance with GELF chunking
+// protocol. It is safe for concurrent usage.
+type GELFWriter struct {
+	sync.Mutex
+	conn        net.Conn
+	compression int
+	chunkSize   int
+	buf         bytes.Buffer
+}
+
+// DialGELF creates a writer that sends GELF messages to UDP address
+// with optional compression (GELFNoCompression, GELFGzip or
+// GELFZlib). Use it with AsGELF() formatter:
+//
+//	w, err := kiwi.DialGELF("graylog:12201", kiwi.GELFGzip)
+//	kiwi.SinkTo(w, kiwi.AsGELF("")).Start()
+func DialGELF(addr string, compression int) (*GELFWriter, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &GELFWriter{conn: conn, compression: compression, chunkSize: GELFChunkSize}, nil
+}
+
+// ChunkSize sets maximum size of the datagram for the writer.
+func (w *GELFWriter) ChunkSize(size int) *GELFWriter {
+	w.Lock()
+	if size > gelfChunkHeaderLen {
+		w.chunkSize = size
+	}
+	w.Unlock()
+	return w
+}
+
+// Write sends a single GELF message. The trailing newline added by
+// the formatter is not sent.
+func (w *GELFWriter) Write(p []byte) (int, error) {
+	var (
+		n   = len(p)
+		err error
+	)
+	p = bytes.TrimRight(p, "\n")
+	w.Lock()
+	defer w.Unlock()
+	w.buf.Reset()
+	switch w.compression {
+	case GELFGzip:
+		z := gzip.NewWriter(&w.buf)
+		z.Write(p)
+		err = z.Close()
+	case GELFZlib:
+		z := zlib.NewWriter(&w.buf)
+		z.Write(p)
+		err = z.Close()
+	default:
+		w.buf.Write(p)
+	}
+	if err != nil {
+		return 0, err
+	}
+	data := w.buf.Bytes()
+	if len(data) <= w.chunkSize {
+		if _, err = w.conn.Write(data); err != nil {
+			return 0, err
+		}
+		return n, nil
+	}
+	var (
+		payload = w.chunkSize - gelfChunkHeaderLen
+		count   = (len(data) + payload - 1) / payload
+	)
+	if count > gelfMaxChunks {
+		return 0, ErrGELFTooLarge
+	}
+	chunk := make([]byte, w.chunkSize)
+	chunk[0], chunk[1] = 0x1e, 0x0f
+	if _, err = rand.Read(chunk[2:10]); err != nil {
+		return 0, err
+	}
+	chunk[11] = byte(count)
+	for i := 0; i < count; i++ {
+		chunk[10] = byte(i)
+		end := (i + 1) * payload
+		if end > len(data) {
+			end = len(data)
+		}
+		l := copy(chunk[gelfChunkHeaderLen:], data[i*payload:end])
+		if _, err = w.conn.Write(chunk[:gelfChunkHeaderLen+l]); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+// Close closes the connection of the writer.
+func (w *GELFWriter) Close() error {
+	return w.conn.Close()
+}
@@ -0,0 +1,143 @@
+package kiwi
+
+/*
+Copyright (c) 2016-2018, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व
+
+All tests consists of three parts:
+
+- arrange structures and initialize objects for use in tests
+- act on testing object
+- check and assert on results
+
+These parts separated by empty lines in each test function.
+*/
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test of GELF formatter. Message and level should be mapped to GELF
+// fields and other pairs should be prefixed.
+func TestGELF_Format(t *testing.T) {
+	f := AsGELF("test-host")
+	var rec map[string]interface{}
+
+	f.Begin()
+	f.Pair(MessageKey, "hello", StringVal)
+	f.Pair(LevelKey, "error", StringVal)
+	f.Pair("count", "3", IntegerVal)
+	f.Pair("bad key", "x", StringVal)
+	f.Pair("id", "42", StringVal)
+	err := json.Unmarshal(f.Finish(), &rec)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec["version"] != "1.1" || rec["host"] != "test-host" || rec["short_message"] != "hello" {
+		t.Errorf("unexpected record %v", rec)
+	}
+	if rec["level"] != 3.0 || rec["_count"] != 3.0 || rec["_bad_key"] != "x" {
+		t.Errorf("unexpected record %v", rec)
+	}
+	if _, ok := rec["_id"]; ok || rec["__id"] != "42" {
+		t.Errorf("unexpected record %v", rec)
+	}
+}
+
+// Test of chunked and compressed messages sent by GELF writer. The
+// message reassembled from chunks should be the same.
+func TestGELF_WriterChunks(t *testing.T) {
+	for _, compression := range []int{GELFNoCompression, GELFGzip, GELFZlib} {
+		l, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := DialGELF(l.LocalAddr().String(), compression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.ChunkSize(64)
+		var sample []string
+		for i := 0; i < 200; i++ {
+			sample = append(sample, strconv.Itoa(i*7919))
+		}
+		msg := `{"short_message":"` + strings.Join(sample, " ") + `"}`
+
+		if _, err = w.Write([]byte(msg + "\n")); err != nil {
+			t.Fatal(err)
+		}
+
+		var (
+			buf    = make([]byte, 128)
+			chunks = make(map[byte][]byte)
+			count  = -1
+		)
+		l.SetReadDeadline(time.Now().Add(3 * time.Second))
+		for count < 0 || len(chunks) < count {
+			n, _, err := l.ReadFrom(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if buf[0] != 0x1e || buf[1] != 0x0f {
+				t.Fatal("expected chunk header")
+			}
+			count = int(buf[11])
+			chunks[buf[10]] = append([]byte(nil), buf[12:n]...)
+		}
+		var data []byte
+		for i := 0; i < count; i++ {
+			data = append(data, chunks[byte(i)]...)
+		}
+		var r io.Reader = bytes.NewReader(data)
+		switch compression {
+		case GELFGzip:
+			r, _ = gzip.NewReader(r)
+		case GELFZlib:
+			r, _ = zlib.NewReader(r)
+		}
+		result, _ := ioutil.ReadAll(r)
+		if string(result) != msg {
+			t.Errorf("compression %d: unexpected message %q", compression, result)
+		}
+		w.Close()
+		l.Close()
+	}
+}
@@ -87,6 +87,8 @@ next:
 				context = append(context, toPair(ErrorKey, "wrong type for the key"))
 				key = MessageKey
 			}
+		} else if obj, ok := arg.(ObjectMarshaler); ok && !isNilValue(obj) {
+			context = mergePairs(context, marshalPairs(key, obj))
 		} else {
 			p := toPair(key, arg)
 			for i, c := range context {
@@ -1,6 +1,6 @@
 package kiwi
 
-import "fmt"
+import "sync/atomic"
 
 // This file consists of definition of global logging methods.
 
@@ -40,51 +40,25 @@ OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 // If you wish separate contexts and achieve better performance
 // use Logger type instead.
 func Log(kv ...interface{}) {
+	// 0. Nothing to do when nobody accepts the record.
+	if atomic.LoadInt32(&activeSinks) == 0 {
+		return
+	}
 	// 1. Log the context.
 	var record = make([]*Pair, 0, len(context)+len(kv))
 	global.RLock()
-	for _, p := range context {
-		// Evaluate delayed context value here before the output.
-		if p.Eval != nil {
-			record = append(record, &Pair{p.Key, p.Eval.(func() string)(), p.Eval, p.Type})
-		} else {
-			record = append(record, &Pair{p.Key, p.Val, nil, p.Type})
-		}
-	}
+	record = append(record, context...)
 	global.RUnlock()
 	// 2. Log the regular key-value pairs that came in the args.
-	var (
-		key          string
-		shouldBeAKey = true
-	)
-	for _, val := range kv {
-		var p *Pair
-		if shouldBeAKey {
-			switch v := val.(type) {
-			case string:
-				key = v
-			case *Pair:
-				if v.Eval != nil {
-					v.Val = v.Eval.(func() string)()
-				}
-				record = append(record, v)
-				continue
-			default:
-				record = append(record, toPair(ErrorKey, fmt.Sprintf("non a string type (%T) for the key (%v)", val, val)))
-				key = MessageKey
-			}
-		} else {
-			if p = toPair(key, val); p.Eval != nil {
-				p.Val = p.Eval.(func() string)()
-			}
-			record = append(record, p)
-		}
-		shouldBeAKey = !shouldBeAKey
-	}
-	// Add the value without the key for odd number for key-val pairs.
-	if !shouldBeAKey && key != MessageKey {
-		record = append(record, toPair(MessageKey, key))
+	record = appendPairs(record, kv)
+	// 3. Skip the record before evaluation of the delayed values
+	// if the filters of the sinks reject it.
+	sinks, ok := delayedSinks(record)
+	if !ok {
+		return
 	}
-	// 2. Pass the record to the collector.
-	sinkRecord(record)
+	// 4. Evaluate the delayed values.
+	evalRecord(record)
+	// 5. Pass the record to the collector.
+	sinkRecord(record, sinks)
 }
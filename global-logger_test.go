@@ -443,6 +443,7 @@ func TestGlobalLogger_WithoutContextPassed_Logfmt(t *testing.T) {
 	kiwi.ResetContext()
 	output := bytes.NewBufferString("")
 	out := kiwi.SinkTo(output, kiwi.AsLogfmt()).Start()
+	defer out.Close()
 
 	// add the context
 	kiwi.With("key1", "value")
@@ -462,6 +463,7 @@ func TestGlobalLogger_ResetContext_Logfmt(t *testing.T) {
 	kiwi.ResetContext()
 	output := bytes.NewBufferString("")
 	out := kiwi.SinkTo(output, kiwi.AsLogfmt()).Start()
+	defer out.Close()
 
 	// add the context
 	kiwi.With("key1", "value")
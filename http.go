@@ -0,0 +1,127 @@
+package kiwi
+
+// This file consists of the sink that sends the records in batches over HTTP.
+
+/* Copyright (c) 2016-2020, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व */
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// HTTPOptions defines the properties of the requests for HTTP sink.
+type HTTPOptions struct {
+	BatchOptions
+	// Headers added to each request. Content-Type by default is
+	// "application/x-ndjson".
+	Headers http.Header
+	// Gzip compresses the bodies of the requests.
+	Gzip bool
+	// Client used for the requests, http.DefaultClient by default.
+	Client *http.Client
+}
+
+// SinkToHTTP creates a new sink that collects formatted records and
+// POSTs them to the URL in batches. The body of the request is just
+// the records one after another so with AsJSON() formatter it is
+// NDJSON. The requests failed with 5xx status or with network errors
+// are retried with backoff. The batch is flushed when it reaches
+// the limits defined by the options, by the interval and finally when
+// the sink closed.
+//
+// As for SinkTo() the sink should be started explicitly.
+func SinkToHTTP(url string, fn Formatter, opts HTTPOptions) *Sink {
+	var (
+		c = &httpClient{url: url, opts: opts}
+		w = newBatchWriter(opts.BatchOptions, c.post)
+	)
+	if c.opts.Client == nil {
+		c.opts.Client = http.DefaultClient
+	}
+	var sink = SinkTo(w, fn)
+	sink.closer = w
+	return sink
+}
+
+type httpClient struct {
+	url  string
+	opts HTTPOptions
+	body bytes.Buffer
+	gz   bytes.Buffer
+}
+
+func (c *httpClient) post(batch [][]byte) error {
+	c.body.Reset()
+	for _, rec := range batch {
+		c.body.Write(rec)
+	}
+	_, err := c.do(c.body.Bytes(), "application/x-ndjson")
+	return err
+}
+
+// do posts the body and returns the body of the response. The errors
+// that allow the retry are wrapped as retryableError.
+func (c *httpClient) do(body []byte, contentType string) ([]byte, error) {
+	if c.opts.Gzip {
+		c.gz.Reset()
+		z := gzip.NewWriter(&c.gz)
+		z.Write(body)
+		z.Close()
+		body = c.gz.Bytes()
+	}
+	req, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if c.opts.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for key, vals := range c.opts.Headers {
+		req.Header[key] = vals
+	}
+	resp, err := c.opts.Client.Do(req)
+	if err != nil {
+		return nil, retryableError{err}
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 500:
+		return nil, retryableError{fmt.Errorf("%s: %s", c.url, resp.Status)}
+	case resp.StatusCode >= 300:
+		return nil, fmt.Errorf("%s: %s", c.url, resp.Status)
+	}
+	return data, err
+}
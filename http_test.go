@@ -0,0 +1,167 @@
+package kiwi
+
+/*
+Copyright (c) 2016-2018, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व
+
+All tests consists of three parts:
+
+- arrange structures and initialize objects for use in tests
+- act on testing object
+- check and assert on results
+
+These parts separated by empty lines in each test function.
+*/
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type httpRecorder struct {
+	sync.Mutex
+	bodies  []string
+	headers []http.Header
+	fails   int
+	status  int
+}
+
+func (h *httpRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.Lock()
+	defer h.Unlock()
+	if h.fails > 0 {
+		h.fails--
+		w.WriteHeader(h.status)
+		return
+	}
+	body := r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		body, _ = gzip.NewReader(r.Body)
+	}
+	data, _ := ioutil.ReadAll(body)
+	h.bodies = append(h.bodies, string(data))
+	h.headers = append(h.headers, r.Header)
+}
+
+func (h *httpRecorder) requests() []string {
+	h.Lock()
+	defer h.Unlock()
+	return append([]string(nil), h.bodies...)
+}
+
+// Test of HTTP sink. The batch should be flushed by the number of
+// records and the rest should be flushed on close.
+func TestHTTP_BatchByCount(t *testing.T) {
+	h := &httpRecorder{}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	log := New()
+	out := SinkToHTTP(srv.URL, AsJSON(), HTTPOptions{
+		BatchOptions: BatchOptions{MaxRecords: 2, Interval: time.Hour},
+		Headers:      http.Header{"X-Token": {"secret"}},
+	}).Start()
+
+	log.Log("n", 1)
+	log.Log("n", 2)
+	log.Log("n", 3)
+
+	out.Close()
+	bodies := h.requests()
+	if len(bodies) != 2 {
+		t.Fatalf("unexpected requests %q", bodies)
+	}
+	var n []int
+	for _, body := range bodies {
+		for _, line := range strings.Split(strings.TrimSuffix(body, "\n"), "\n") {
+			var rec struct{ N int }
+			if err := json.Unmarshal([]byte(line), &rec); err != nil {
+				t.Fatalf("invalid line %q: %s", line, err)
+			}
+			n = append(n, rec.N)
+		}
+	}
+	if len(n) != 3 || n[0] != 1 || n[1] != 2 || n[2] != 3 || strings.Count(bodies[0], "\n") != 2 {
+		t.Errorf("unexpected requests %q", bodies)
+	}
+	if h.headers[0].Get("X-Token") != "secret" || h.headers[0].Get("Content-Type") != "application/x-ndjson" {
+		t.Errorf("unexpected headers %v", h.headers[0])
+	}
+}
+
+// Test of HTTP sink with gzip bodies. Requests failed with 5xx should
+// be retried.
+func TestHTTP_RetryGzip(t *testing.T) {
+	h := &httpRecorder{fails: 2, status: http.StatusServiceUnavailable}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	log := New()
+	out := SinkToHTTP(srv.URL, AsLogfmt(), HTTPOptions{
+		BatchOptions: BatchOptions{Interval: 10 * time.Millisecond, Backoff: time.Millisecond},
+		Gzip:         true,
+	}).Start()
+	defer out.Close()
+
+	log.Log("k", "v")
+
+	deadline := time.Now().Add(3 * time.Second)
+	for len(h.requests()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if bodies := h.requests(); len(bodies) != 1 || bodies[0] != "k=\"v\" \n" {
+		t.Errorf("unexpected requests %q", bodies)
+	}
+}
+
+// Test of HTTP sink with client error. The request should not be
+// retried and the error should be reported.
+func TestHTTP_ClientError(t *testing.T) {
+	h := &httpRecorder{fails: 1, status: http.StatusBadRequest}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	log := New()
+	var errs []error
+	out := SinkToHTTP(srv.URL, AsLogfmt(), HTTPOptions{
+		BatchOptions: BatchOptions{Interval: time.Hour, OnError: func(err error) { errs = append(errs, err) }},
+	}).Start()
+
+	log.Log("k", "v")
+
+	out.Close()
+	if len(errs) != 1 || len(h.requests()) != 0 || h.fails != 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+}
@@ -0,0 +1,163 @@
+package kiwi
+
+// This file consists of the sink for journald native protocol.
+
+/* Copyright (c) 2016-2020, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व */
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// JournalSocket is the default path of journald socket for the native
+// protocol.
+var JournalSocket = "/run/systemd/journal/socket"
+
+// SinkToJournal creates a new sink that sends the records to journald
+// with its native protocol. If the path is empty then JournalSocket
+// used. The keys of the pairs converted to journal fields: they are
+// uppercased and the characters not allowed in the field names
+// replaced with "_", the leading underscores are removed. The value of
+// MessageKey becomes MESSAGE and the value of LevelKey mapped to
+// PRIORITY. Other keys that give the names of these fields are
+// prefixed with "F_". Multiline values are encoded
+// in binary safe way. Note that the records that exceed maximum
+// datagram size are not supported as they require passing of the
+// file descriptor.
+//
+// As for SinkTo() the sink should be started explicitly. The
+// connection is closed when the sink closed.
+func SinkToJournal(path string) (*Sink, error) {
+	if path == "" {
+		path = JournalSocket
+	}
+	// The writer of syslog reconnects the same way as required for
+	// journald, the datagrams are sent without framing.
+	var w = &syslogWriter{network: "unixgram", addr: path}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	var sink = SinkTo(w, newJournalFormat())
+	sink.closer = w
+	return sink, nil
+}
+
+type formatJournal struct {
+	line       *bytes.Buffer
+	identifier string
+	priority   int
+	field      []byte
+}
+
+func newJournalFormat() *formatJournal {
+	return &formatJournal{
+		line:       bytes.NewBuffer(make([]byte, 512)),
+		identifier: filepath.Base(os.Args[0]),
+	}
+}
+
+func (f *formatJournal) Begin() {
+	f.line.Reset()
+	f.priority = sevInfo
+}
+
+func (f *formatJournal) Pair(key, val string, valType int) {
+	switch key {
+	case MessageKey:
+		f.writeField("MESSAGE", val)
+		return
+	case LevelKey:
+		// The original value of the level is kept in the
+		// field along with the priority.
+		f.priority = syslogSeverity(val)
+	}
+	if name := f.fieldName(key); name != "" {
+		f.writeField(name, val)
+	}
+}
+
+func (f *formatJournal) Finish() []byte {
+	f.writeField("PRIORITY", strconv.Itoa(f.priority))
+	f.writeField("SYSLOG_IDENTIFIER", f.identifier)
+	return f.line.Bytes()
+}
+
+// fieldName converts the key to the name of journal field. The fields
+// started with underscore are trusted fields in journald so the
+// leading underscores are removed after the conversion. The names of
+// the fields set by the formatter itself are prefixed with "F_".
+func (f *formatJournal) fieldName(key string) string {
+	f.field = f.field[:0]
+	for i := 0; i < len(key) && len(f.field) < 64; i++ {
+		c := key[i]
+		switch {
+		case c >= 'a' && c <= 'z':
+			c -= 'a' - 'A'
+		case c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		default:
+			c = '_'
+		}
+		if len(f.field) == 0 {
+			if c == '_' {
+				continue
+			}
+			if c >= '0' && c <= '9' {
+				f.field = append(f.field, 'F', '_')
+			}
+		}
+		f.field = append(f.field, c)
+	}
+	switch string(f.field) {
+	case "MESSAGE", "PRIORITY", "SYSLOG_IDENTIFIER":
+		f.field = append([]byte("F_"), f.field...)
+	}
+	return string(f.field)
+}
+
+func (f *formatJournal) writeField(name, val string) {
+	f.line.WriteString(name)
+	if strings.IndexByte(val, '\n') < 0 {
+		f.line.WriteRune('=')
+		f.line.WriteString(val)
+		f.line.WriteRune('\n')
+		return
+	}
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(val)))
+	f.line.WriteRune('\n')
+	f.line.Write(size[:])
+	f.line.WriteString(val)
+	f.line.WriteRune('\n')
+}
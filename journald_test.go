@@ -0,0 +1,98 @@
+package kiwi
+
+/*
+Copyright (c) 2016-2018, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व
+
+All tests consists of three parts:
+
+- arrange structures and initialize objects for use in tests
+- act on testing object
+- check and assert on results
+
+These parts separated by empty lines in each test function.
+*/
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test of journald sink with local unixgram listener. Keys should be
+// converted to the journal fields and multiline values should be
+// encoded with their size.
+func TestJournal_Sink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kiwi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "socket")
+	l, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	log := New()
+	out, err := SinkToJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.Start()
+
+	log.Log(MessageKey, "hello", LevelKey, "warning", "user-id", 42, "_pid", 1, "-uid", 2, "priority", 3, "Message", "x", "9lives", true, "trace", "line1\nline2")
+
+	out.Close()
+	buf := make([]byte, 1024)
+	l.SetReadDeadline(time.Now().Add(3 * time.Second))
+	n, err := l.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "MESSAGE=hello\n" +
+		"LEVEL=warning\n" +
+		"USER_ID=42\n" +
+		"PID=1\n" +
+		"UID=2\n" +
+		"F_PRIORITY=3\n" +
+		"F_MESSAGE=x\n" +
+		"F_9LIVES=true\n" +
+		"TRACE\n\x0b\x00\x00\x00\x00\x00\x00\x00line1\nline2\n" +
+		"PRIORITY=4\n" +
+		"SYSLOG_IDENTIFIER="
+	if !strings.HasPrefix(string(buf[:n]), expected) {
+		t.Errorf("unexpected message %q", buf[:n])
+	}
+}
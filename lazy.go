@@ -0,0 +1,125 @@
+package kiwi
+
+// This file consists of the delayed evaluation of the values.
+
+/* Copyright (c) 2016-2020, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व */
+
+import (
+	"fmt"
+	"time"
+)
+
+// Lazy marks the function as the delayed value. The function called
+// only when the record is logged and its result converted the same
+// way as the values passed to Log() so the type of the value is kept:
+//
+//	log.Log("stats", kiwi.Lazy(func() interface{} { return cache.Stats() }))
+//
+// The functions returning string, int, int64, float64, bool,
+// time.Time or error could be passed as the delayed values without
+// the wrapper. If the function panics the pair gets the error value
+// describing the panic instead of the value.
+func Lazy(fn func() interface{}) func() interface{} {
+	return fn
+}
+
+// lazyType returns the type hint for the delayed value. It returns
+// false if the value is not the delayed value.
+func lazyType(val interface{}) (int, bool) {
+	switch val.(type) {
+	case func() string, func() interface{}:
+		return StringVal, true
+	case func() int, func() int64:
+		return IntegerVal, true
+	case func() float64:
+		return FloatVal, true
+	case func() bool:
+		return BooleanVal, true
+	case func() time.Time:
+		return TimeVal, true
+	case func() error:
+		return ErrorVal, true
+	}
+	return 0, false
+}
+
+// evalPair evaluates the delayed value of the pair. The result is the
+// new pair so the pair of the context could be evaluated again for
+// the next record.
+func evalPair(p *Pair) (result *Pair) {
+	defer func() {
+		if r := recover(); r != nil {
+			err := fmt.Errorf("panic in the delayed value: %v", r)
+			result = &Pair{Key: p.Key, Val: errorToJSON(err), Eval: p.Eval, Type: ErrorVal}
+		}
+	}()
+	var val interface{}
+	switch fn := p.Eval.(type) {
+	case func() string:
+		// The type of the value kept as is because the
+		// helpers (for example timestamp.Set()) give it
+		// the type hint.
+		return &Pair{Key: p.Key, Val: fn(), Eval: p.Eval, Type: p.Type}
+	case func() interface{}:
+		val = fn()
+	case func() int:
+		val = fn()
+	case func() int64:
+		val = fn()
+	case func() float64:
+		val = fn()
+	case func() bool:
+		val = fn()
+	case func() time.Time:
+		val = fn()
+	case func() error:
+		val = fn()
+	default:
+		return p
+	}
+	result = toPair(p.Key, val)
+	if result.Eval != nil {
+		// The delayed value returned by the delayed value
+		// is not evaluated again.
+		result = &Pair{Key: p.Key, Val: "", Type: result.Type}
+	}
+	result.Eval = p.Eval
+	return result
+}
+
+// evalRecord evaluates the delayed values of the record in place.
+func evalRecord(record []*Pair) {
+	for i, p := range record {
+		if p.Eval != nil {
+			record[i] = evalPair(p)
+		}
+	}
+}
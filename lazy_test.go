@@ -0,0 +1,102 @@
+package kiwi
+
+/*
+Copyright (c) 2016-2018, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व
+
+All tests consists of three parts:
+
+- arrange structures and initialize objects for use in tests
+- act on testing object
+- check and assert on results
+
+These parts separated by empty lines in each test function.
+*/
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Test of the delayed values of the different types. They should be
+// evaluated on logging and formatted accordingly with their types.
+func TestLazy_Types_JSON(t *testing.T) {
+	output := bytes.NewBufferString("")
+	log := New()
+	out := SinkTo(output, AsJSON()).Start()
+	var calls int
+	log.With("n", func() int { calls++; return calls })
+
+	log.Log("any", Lazy(func() interface{} { return 1.5 }), "ok", func() bool { return true }, "err", func() error { return errors.New("oops") })
+	log.Log()
+
+	out.Close()
+	if !strings.Contains(output.String(), `"n":1`) || !strings.Contains(output.String(), `"n":2`) ||
+		!strings.Contains(output.String(), `"any":1.5e+00`) || !strings.Contains(output.String(), `"ok":true`) ||
+		!strings.Contains(output.String(), `"err":{"message":"oops"`) {
+		println(output.String())
+		t.Fail()
+	}
+}
+
+// Test of the delayed value passed to Logger.Log(). It should be
+// evaluated before the output.
+func TestLazy_StringInLog_Logfmt(t *testing.T) {
+	output := bytes.NewBufferString("")
+	log := New()
+	out := SinkTo(output, AsLogfmt()).Start()
+
+	log.Log("k", func() string { return "v" })
+
+	out.Close()
+	if strings.TrimSpace(output.String()) != `k="v"` {
+		println(output.String())
+		t.Fail()
+	}
+}
+
+// Test of the panic in the delayed value. It should be logged as the
+// error instead of crashing.
+func TestLazy_Panic_Logfmt(t *testing.T) {
+	ResetContext()
+	output := bytes.NewBufferString("")
+	out := SinkTo(output, AsLogfmt()).Start()
+
+	Log("k", Lazy(func() interface{} { panic("boom") }), "next", 1)
+
+	out.Close()
+	if !strings.HasPrefix(strings.TrimSpace(output.String()), `k="panic in the delayed value: boom" k.type=`) ||
+		!strings.Contains(output.String(), "next=1") {
+		println(output.String())
+		t.Fail()
+	}
+}
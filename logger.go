@@ -1,6 +1,9 @@
 package kiwi
 
-import "fmt"
+import (
+	"fmt"
+	"sync/atomic"
+)
 
 // This file consists of Logger related structures and functions.
 
@@ -38,6 +41,11 @@ var (
 	MessageKey = "message"
 	ErrorKey   = "kiwi-error"
 	InfoKey    = "kiwi-info"
+	// LevelKey used by the formatters and sinks that map the
+	// severity of the record to the severity of the protocol
+	// (for example syslog or GELF). It is the same name as
+	// the default name in the level package.
+	LevelKey = "level"
 )
 
 type (
@@ -62,11 +70,17 @@ type (
 	}
 	// Pair is key and value together. They can be used by custom
 	// helpers for example for logging timestamps or something.
+	// Raw keeps the original typed value of the scalars and the
+	// time so the sinks could format it in their own way (see
+	// Sink.FloatFormat() and Sink.TimeLayout()) and the filters
+	// could check it without parsing of the string (see
+	// TypedFilter). It is nil for other values.
 	Pair struct {
 		Key  string
 		Val  string
 		Eval interface{}
 		Type int
+		Raw  interface{}
 	}
 )
 
@@ -106,52 +120,29 @@ func (l *Logger) New() *Logger {
 // Log is the most common method for flushing previously added key-val pairs to an output.
 // After current record is flushed all pairs removed from a record except contextSrc pairs.
 func (l *Logger) Log(keyVals ...interface{}) {
+	// 0. Nothing to do when nobody accepts the record.
+	if atomic.LoadInt32(&activeSinks) == 0 {
+		l.pairs = nil
+		return
+	}
 	// 1. Log the context.
 	var record = make([]*Pair, 0, len(l.context)+len(l.pairs)+len(keyVals))
-	for _, p := range l.context {
-		if p.Eval != nil {
-			// Evaluate delayed context value here before output.
-			record = append(record, &Pair{p.Key, p.Eval.(func() string)(), p.Eval, p.Type})
-		} else {
-			record = append(record, &Pair{p.Key, p.Val, nil, p.Type})
-		}
-	}
+	record = append(record, l.context...)
 	// 2. Log the regular key-value pairs that added before by Add() calls.
-	for _, p := range l.pairs {
-		if p.Eval != nil {
-			record = append(record, &Pair{p.Key, p.Eval.(func() string)(), p.Eval, p.Type})
-		} else {
-			record = append(record, &Pair{p.Key, p.Val, nil, p.Type})
-		}
-	}
+	record = append(record, l.pairs...)
 	// 3. Log the regular key-value pairs that come in the args.
-	var (
-		key          string
-		shouldBeAKey = true
-	)
-	for _, val := range keyVals {
-		if shouldBeAKey {
-			switch v := val.(type) {
-			case string:
-				key = v
-			case *Pair:
-				record = append(record, v)
-				continue
-			default:
-				record = append(record, toPair(ErrorKey, fmt.Sprintf("non a string type (%T) for the key (%v)", val, val)))
-				key = MessageKey
-			}
-		} else {
-			record = append(record, toPair(key, val))
-		}
-		shouldBeAKey = !shouldBeAKey
-	}
-	if !shouldBeAKey && key != MessageKey {
-		record = append(record, toPair(MessageKey, key))
-	}
-	// 4. Pass the record to the collector.
-	sinkRecord(record)
+	record = appendPairs(record, keyVals)
 	l.pairs = nil
+	// 4. Skip the record before evaluation of the delayed values
+	// if the filters of the sinks reject it.
+	sinks, ok := delayedSinks(record)
+	if !ok {
+		return
+	}
+	// 5. Evaluate the delayed values.
+	evalRecord(record)
+	// 6. Pass the record to the collector.
+	sinkRecord(record, sinks)
 }
 
 // Add a new key-value pairs to the log record. If a key already added then value will be
@@ -172,10 +163,15 @@ func (l *Logger) Add(keyVals ...interface{}) *Logger {
 			case *Pair:
 				l.pairs = append(l.pairs, v)
 				continue
+			case []*Pair:
+				l.pairs = append(l.pairs, v...)
+				continue
 			default:
 				l.pairs = append(l.pairs, toPair(ErrorKey, fmt.Sprintf("non a string type (%T) for the key (%v)", val, val)))
 				continue
 			}
+		} else if obj, ok := val.(ObjectMarshaler); ok && !isNilValue(obj) {
+			l.pairs = append(l.pairs, marshalPairs(key, obj)...)
 		} else {
 			l.pairs = append(l.pairs, toPair(key, val))
 		}
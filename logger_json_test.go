@@ -59,7 +59,7 @@ func TestLogger_LogStringValue_JSON(t *testing.T) {
 	log.Log("k", "The sample string with a lot of spaces.")
 
 	out.Flush()
-	if strings.TrimSpace(output.String()) != `{"k":"The sample string with a lot of spaces.", }` {
+	if strings.TrimSpace(output.String()) != `{"k":"The sample string with a lot of spaces."}` {
 		t.Fail()
 	}
 }
@@ -74,7 +74,7 @@ func TestLogger_LogBytesValue_JSON(t *testing.T) {
 	log.Log("k", []byte("The sample string with a lot of spaces."))
 
 	out.Flush()
-	if strings.TrimSpace(output.String()) != `{"k":"The sample string with a lot of spaces.", }` {
+	if strings.TrimSpace(output.String()) != `{"k":"The sample string with a lot of spaces."}` {
 		t.Fail()
 	}
 }
@@ -89,7 +89,7 @@ func TestLogger_LogIntValue_JSON(t *testing.T) {
 	log.Log("k", 123)
 
 	out.Flush()
-	if strings.TrimSpace(output.String()) != `{"k":123, }` {
+	if strings.TrimSpace(output.String()) != `{"k":123}` {
 		t.Fail()
 	}
 }
@@ -104,7 +104,7 @@ func TestLogger_LogNegativeIntValue_JSON(t *testing.T) {
 	log.Log("k", -123)
 
 	out.Flush()
-	if strings.TrimSpace(output.String()) != `{"k":-123, }` {
+	if strings.TrimSpace(output.String()) != `{"k":-123}` {
 		t.Fail()
 	}
 }
@@ -119,7 +119,7 @@ func TestLogger_LogFloatValue_JSON(t *testing.T) {
 	log.Log("k", 3.14159265359)
 
 	out.Flush()
-	if strings.TrimSpace(output.String()) != `{"k":3.14159265359e+00, }` {
+	if strings.TrimSpace(output.String()) != `{"k":3.14159265359e+00}` {
 		t.Fail()
 	}
 }
@@ -135,7 +135,7 @@ func TestLogger_LogFixedFloatValue_JSON(t *testing.T) {
 	log.Log("k", 3.14159265359)
 
 	out.Flush()
-	if strings.TrimSpace(output.String()) != `{"k":3.14159265359, }` {
+	if strings.TrimSpace(output.String()) != `{"k":3.14159265359}` {
 		t.Fail()
 	}
 	// Turn back to default format.
@@ -152,7 +152,7 @@ func TestLogger_LogBoolValue_JSON(t *testing.T) {
 	log.Log("k", true, "k2", false)
 
 	out.Flush()
-	if strings.TrimSpace(output.String()) != `{"k":true, "k2":false, }` {
+	if strings.TrimSpace(output.String()) != `{"k":true, "k2":false}` {
 		t.Fail()
 	}
 }
@@ -167,7 +167,7 @@ func TestLogger_LogComplexValue_JSON(t *testing.T) {
 	log.Log("k", .12345E+5i, "k2", 1.e+0i)
 
 	out.Flush()
-	if strings.TrimSpace(output.String()) != `{"k":(0.000000+12345.000000i), "k2":(0.000000+1.000000i), }` {
+	if strings.TrimSpace(output.String()) != `{"k":(0.000000+12345.000000i), "k2":(0.000000+1.000000i)}` {
 		t.Fail()
 	}
 }
@@ -184,7 +184,7 @@ func TestLogger_LogTimeValue_JSON(t *testing.T) {
 	log.Log("k", value)
 
 	out.Flush()
-	expect := fmt.Sprintf(`{"k":"%s", }`, valueString)
+	expect := fmt.Sprintf(`{"k":"%s"}`, valueString)
 	got := strings.TrimSpace(output.String())
 	if got != expect {
 		t.Logf("expected %s got %v", expect, got)
@@ -201,7 +201,7 @@ func TestLogger_LogIntKeyInvalid_JSON(t *testing.T) {
 	log.Log(123, 456)
 
 	out.Flush()
-	expect := `{"kiwi-error":"non a string type (int) for the key (123)", "message":456, }`
+	expect := `{"kiwi-error":"non a string type (int) for the key (123)", "message":456}`
 	got := strings.TrimSpace(output.String())
 	if got != expect {
 		t.Logf("expected %s got %v", expect, got)
@@ -221,7 +221,7 @@ func TestLogger_LogThreeIntKeysInvalid_JSON(t *testing.T) {
 	log.Log(123, 456, 789)
 
 	out.Flush()
-	expect := `{"kiwi-error":"non a string type (int) for the key (123)", "message":456, "kiwi-error":"non a string type (int) for the key (789)", }`
+	expect := `{"kiwi-error":"non a string type (int) for the key (123)", "message":456, "kiwi-error":"non a string type (int) for the key (789)"}`
 	got := strings.TrimSpace(output.String())
 	if got != expect {
 		t.Logf("expected %s got %v", expect, got)
@@ -241,7 +241,7 @@ func TestLogger_LogFourIntKeysInvalid_JSON(t *testing.T) {
 	log.Log(12, 34, 56, 78)
 
 	out.Flush()
-	expect := `{"kiwi-error":"non a string type (int) for the key (12)", "message":34, "kiwi-error":"non a string type (int) for the key (56)", "message":78, }`
+	expect := `{"kiwi-error":"non a string type (int) for the key (12)", "message":34, "kiwi-error":"non a string type (int) for the key (56)", "message":78}`
 	got := strings.TrimSpace(output.String())
 	if got != expect {
 		t.Logf("expected %s got %v", expect, got)
@@ -259,7 +259,7 @@ func TestLogger_AddMixChained_JSON(t *testing.T) {
 	log.Add("k", "value2").Add("k2", 123).Add("k3", 3.14159265359).Log()
 
 	out.Flush()
-	expect := `{"k":"value2", "k2":123, "k3":3.14159265359e+00, }`
+	expect := `{"k":"value2", "k2":123, "k3":3.14159265359e+00}`
 	got := strings.TrimSpace(output.String())
 	if got != expect {
 		t.Logf("expected %s got %v", expect, got)
@@ -278,7 +278,7 @@ func TestLogger_WithContextPassed_JSON(t *testing.T) {
 	log.Log("key2", "value")
 
 	out.Flush()
-	expect := `{"key1":"value", "key2":"value", }`
+	expect := `{"key1":"value", "key2":"value"}`
 	got := strings.TrimSpace(output.String())
 	if got != expect {
 		t.Logf("expected %s got %v", expect, got)
@@ -302,7 +302,7 @@ func TestLogger_WithoutContextPassed_JSON(t *testing.T) {
 	log.Without("key1").Log()
 
 	out.Flush()
-	if strings.TrimSpace(output.String()) != `{"key2":"value", }` {
+	if strings.TrimSpace(output.String()) != `{"key2":"value"}` {
 		t.Fail()
 	}
 }
@@ -322,7 +322,7 @@ func TestLogger_ResetContext_JSON(t *testing.T) {
 	log.ResetContext().Log()
 
 	out.Flush()
-	if strings.TrimSpace(output.String()) != `{"key2":"value", }` {
+	if strings.TrimSpace(output.String()) != `{"key2":"value"}` {
 		t.Fail()
 	}
 }
@@ -0,0 +1,200 @@
+package kiwi
+
+// This file consists of the sink for Grafana Loki push API.
+
+/* Copyright (c) 2016-2020, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व */
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LokiPushPath is the path of Loki push API.
+const LokiPushPath = "/loki/api/v1/push"
+
+// LokiDefaultLabel used as the stream label for the records without
+// any of the labels because Loki requires at least one label for the
+// stream.
+var LokiDefaultLabel = [2]string{"job", "kiwi"}
+
+// SinkToLoki creates a new sink that pushes the records to Grafana
+// Loki. The url is the address of Loki server, the push API path
+// appended to it if it is missed. The values of the label keys
+// become stream labels, so choose the keys with low cardinality like
+// "app" or "level". The rest of the pairs of the record becomes the
+// line in logfmt format. The records are sent in batches and grouped
+// by their label sets in each batch.
+//
+// As for SinkTo() the sink should be started explicitly.
+func SinkToLoki(url string, labels []string, opts HTTPOptions) *Sink {
+	if !strings.HasSuffix(url, LokiPushPath) {
+		url = strings.TrimRight(url, "/") + LokiPushPath
+	}
+	var (
+		c = &httpClient{url: url, opts: opts}
+		w = &lokiWriter{client: c, labels: make(map[string]string, len(labels))}
+		f = &formatLoki{labels: make(map[string]bool, len(labels)), line: AsLogfmt()}
+	)
+	if c.opts.Client == nil {
+		c.opts.Client = http.DefaultClient
+	}
+	for _, key := range labels {
+		w.labels[key] = lokiLabelName(key)
+		f.labels[key] = true
+	}
+	w.batch = newBatchWriter(opts.BatchOptions, w.push)
+	var sink = SinkTo(w, f)
+	sink.closer = w.batch
+	return sink
+}
+
+// formatLoki formats the line of the record without the labels.
+type formatLoki struct {
+	labels map[string]bool
+	line   *formatLogfmt
+}
+
+func (f *formatLoki) Begin() {
+	f.line.Begin()
+}
+
+func (f *formatLoki) Pair(key, val string, valType int) {
+	if !f.labels[key] {
+		f.line.Pair(key, val, valType)
+	}
+}
+
+func (f *formatLoki) Finish() []byte {
+	return bytes.TrimRight(f.line.line.Bytes(), " ")
+}
+
+// lokiWriter passes the records to the batch as the entries of
+// three lines: the labels of the stream, the timestamp and the line.
+type lokiWriter struct {
+	client *httpClient
+	batch  *batchWriter
+	labels map[string]string
+	names  []string
+	entry  bytes.Buffer
+	body   bytes.Buffer
+}
+
+func (w *lokiWriter) Write(p []byte) (int, error) {
+	return w.writeRecord(nil, p)
+}
+
+func (w *lokiWriter) writeRecord(record []*Pair, data []byte) (int, error) {
+	var ts = time.Now().UnixNano()
+	w.names = w.names[:0]
+	for _, p := range record {
+		if name, ok := w.labels[p.Key]; ok {
+			w.names = append(w.names, name+"\x00"+p.Val)
+		}
+	}
+	if len(w.names) == 0 {
+		w.names = append(w.names, LokiDefaultLabel[0]+"\x00"+LokiDefaultLabel[1])
+	}
+	sort.Strings(w.names)
+	w.entry.Reset()
+	w.entry.WriteRune('{')
+	for i, l := range w.names {
+		if i > 0 {
+			w.entry.WriteRune(',')
+		}
+		sep := strings.IndexByte(l, 0)
+		writeJSONString(&w.entry, l[:sep])
+		w.entry.WriteRune(':')
+		writeJSONString(&w.entry, l[sep+1:])
+	}
+	w.entry.WriteString("}\n")
+	w.entry.WriteString(strconv.FormatInt(ts, 10))
+	w.entry.WriteRune('\n')
+	w.entry.Write(data)
+	return w.batch.Write(w.entry.Bytes())
+}
+
+// push groups the entries of the batch by the streams and sends them.
+func (w *lokiWriter) push(batch [][]byte) error {
+	var (
+		streams = make(map[string]*bytes.Buffer)
+		order   []string
+	)
+	for _, entry := range batch {
+		var (
+			labelsEnd = bytes.IndexByte(entry, '\n')
+			tsEnd     = labelsEnd + 1 + bytes.IndexByte(entry[labelsEnd+1:], '\n')
+			labels    = string(entry[:labelsEnd])
+			values    = streams[labels]
+		)
+		if values == nil {
+			values = &bytes.Buffer{}
+			streams[labels] = values
+			order = append(order, labels)
+		} else {
+			values.WriteRune(',')
+		}
+		values.WriteString(`["`)
+		values.Write(entry[labelsEnd+1 : tsEnd])
+		values.WriteString(`",`)
+		writeJSONString(values, string(entry[tsEnd+1:]))
+		values.WriteRune(']')
+	}
+	w.body.Reset()
+	w.body.WriteString(`{"streams":[`)
+	for i, labels := range order {
+		if i > 0 {
+			w.body.WriteRune(',')
+		}
+		w.body.WriteString(`{"stream":`)
+		w.body.WriteString(labels)
+		w.body.WriteString(`,"values":[`)
+		w.body.Write(streams[labels].Bytes())
+		w.body.WriteString("]}")
+	}
+	w.body.WriteString("]}")
+	_, err := w.client.do(w.body.Bytes(), "application/json")
+	return err
+}
+
+// lokiLabelName converts the key to the name allowed for Loki labels.
+func lokiLabelName(key string) string {
+	var name = []byte(key)
+	for i, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || i > 0 && c >= '0' && c <= '9') {
+			name[i] = '_'
+		}
+	}
+	return string(name)
+}
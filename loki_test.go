@@ -0,0 +1,99 @@
+package kiwi
+
+/*
+Copyright (c) 2016-2018, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व
+
+All tests consists of three parts:
+
+- arrange structures and initialize objects for use in tests
+- act on testing object
+- check and assert on results
+
+These parts separated by empty lines in each test function.
+*/
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Test of Loki sink with a local stand-in of push API. The records
+// should be grouped by the label sets.
+func TestLoki_Push(t *testing.T) {
+	var (
+		path string
+		push struct {
+			Streams []struct {
+				Stream map[string]string
+				Values [][2]string
+			}
+		}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&push); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	log := New()
+	out := SinkToLoki(srv.URL, []string{"app", "level"}, HTTPOptions{
+		BatchOptions: BatchOptions{Interval: time.Hour},
+	}).Start()
+
+	log.Log("app", "api", "level", "info", "msg", "first")
+	log.Log("app", "api", "level", "error", "msg", "second")
+	log.Log("level", "info", "msg", "third", "app", "api")
+	log.Log("msg", "fourth")
+
+	out.Close()
+	if path != LokiPushPath {
+		t.Errorf("unexpected path %s", path)
+	}
+	if len(push.Streams) != 3 {
+		t.Fatalf("unexpected streams %+v", push.Streams)
+	}
+	s := push.Streams[0]
+	if s.Stream["app"] != "api" || s.Stream["level"] != "info" || len(s.Values) != 2 ||
+		s.Values[0][1] != `msg="first"` || s.Values[1][1] != `msg="third"` {
+		t.Errorf("unexpected stream %+v", s)
+	}
+	if s = push.Streams[1]; s.Stream["level"] != "error" || len(s.Values) != 1 || s.Values[0][1] != `msg="second"` {
+		t.Errorf("unexpected stream %+v", s)
+	}
+	if s = push.Streams[2]; s.Stream["job"] != "kiwi" || len(s.Values) != 1 {
+		t.Errorf("unexpected stream %+v", s)
+	}
+}
@@ -0,0 +1,195 @@
+package kiwi
+
+// This file consists of the interface for the types that log themselves
+// as several pairs.
+
+/* Copyright (c) 2016-2020, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व */
+
+import (
+	"bytes"
+	"strconv"
+	"time"
+)
+
+type (
+	// ObjectMarshaler allows the custom types log themselves as
+	// several typed pairs instead of the single value. The pairs
+	// added to the encoder get the key of the value as the
+	// prefix: the object logged with the key "req" and added the
+	// pair "method" outputs "req.method" pair. The JSON formatter
+	// gets the pairs as the nested object when the object passed
+	// in the pair created by the custom helper.
+	ObjectMarshaler interface {
+		MarshalKiwi(enc PairEncoder)
+	}
+	// PairEncoder accepts the pairs from ObjectMarshaler. The
+	// typed methods allow to avoid the conversion by reflection.
+	PairEncoder interface {
+		AddString(key, val string)
+		AddInt(key string, val int64)
+		AddUint(key string, val uint64)
+		AddFloat(key string, val float64)
+		AddBool(key string, val bool)
+		AddTime(key string, val time.Time)
+		// AddValue converts the value the same way as the
+		// values passed to Log().
+		AddValue(key string, val interface{})
+		// AddObject adds the nested object with the key as
+		// the additional prefix.
+		AddObject(key string, obj ObjectMarshaler)
+	}
+)
+
+// marshalPairs expands the object to the list of the pairs with the
+// keys prefixed by the key of the object.
+func marshalPairs(key string, obj ObjectMarshaler) []*Pair {
+	var enc = pairEncoder{prefix: key + "."}
+	obj.MarshalKiwi(&enc)
+	return enc.pairs
+}
+
+// pairEncoder collects the pairs from ObjectMarshaler for the record.
+type pairEncoder struct {
+	prefix string
+	pairs  []*Pair
+}
+
+func (e *pairEncoder) AddString(key, val string) {
+	e.pairs = append(e.pairs, &Pair{Key: e.prefix + key, Val: val, Type: StringVal, Raw: val})
+}
+
+func (e *pairEncoder) AddInt(key string, val int64) {
+	e.pairs = append(e.pairs, &Pair{Key: e.prefix + key, Val: strconv.FormatInt(val, 10), Type: IntegerVal, Raw: val})
+}
+
+func (e *pairEncoder) AddUint(key string, val uint64) {
+	e.pairs = append(e.pairs, &Pair{Key: e.prefix + key, Val: strconv.FormatUint(val, 10), Type: IntegerVal, Raw: val})
+}
+
+func (e *pairEncoder) AddFloat(key string, val float64) {
+	e.pairs = append(e.pairs, &Pair{Key: e.prefix + key, Val: strconv.FormatFloat(val, FloatFormat, -1, 64), Type: FloatVal, Raw: val})
+}
+
+func (e *pairEncoder) AddBool(key string, val bool) {
+	e.pairs = append(e.pairs, &Pair{Key: e.prefix + key, Val: strconv.FormatBool(val), Type: BooleanVal, Raw: val})
+}
+
+func (e *pairEncoder) AddTime(key string, val time.Time) {
+	e.pairs = append(e.pairs, &Pair{Key: e.prefix + key, Val: val.Format(TimeLayout), Type: TimeVal, Raw: val})
+}
+
+func (e *pairEncoder) AddValue(key string, val interface{}) {
+	if obj, ok := val.(ObjectMarshaler); ok && !isNilValue(obj) {
+		e.AddObject(key, obj)
+		return
+	}
+	e.pairs = append(e.pairs, toPair(e.prefix+key, val))
+}
+
+func (e *pairEncoder) AddObject(key string, obj ObjectMarshaler) {
+	var prefix = e.prefix
+	e.prefix += key + "."
+	obj.MarshalKiwi(e)
+	e.prefix = prefix
+}
+
+// objectEncoder writes the pairs from ObjectMarshaler as JSON object.
+type objectEncoder struct {
+	buf   bytes.Buffer
+	comma bool
+}
+
+// marshalJSON converts the object to JSON. It used when the object
+// converted to the single pair.
+func marshalJSON(obj ObjectMarshaler) string {
+	var enc objectEncoder
+	enc.buf.WriteRune('{')
+	obj.MarshalKiwi(&enc)
+	enc.buf.WriteRune('}')
+	return enc.buf.String()
+}
+
+func (e *objectEncoder) key(key string) {
+	if e.comma {
+		e.buf.WriteRune(',')
+	}
+	e.comma = true
+	writeJSONString(&e.buf, key)
+	e.buf.WriteRune(':')
+}
+
+func (e *objectEncoder) AddString(key, val string) {
+	e.key(key)
+	writeJSONString(&e.buf, val)
+}
+
+func (e *objectEncoder) AddInt(key string, val int64) {
+	e.key(key)
+	e.buf.WriteString(strconv.FormatInt(val, 10))
+}
+
+func (e *objectEncoder) AddUint(key string, val uint64) {
+	e.key(key)
+	e.buf.WriteString(strconv.FormatUint(val, 10))
+}
+
+func (e *objectEncoder) AddFloat(key string, val float64) {
+	e.key(key)
+	writeJSONFloat(&e.buf, val)
+}
+
+func (e *objectEncoder) AddBool(key string, val bool) {
+	e.key(key)
+	e.buf.WriteString(strconv.FormatBool(val))
+}
+
+func (e *objectEncoder) AddTime(key string, val time.Time) {
+	e.key(key)
+	writeJSONString(&e.buf, val.Format(TimeLayout))
+}
+
+func (e *objectEncoder) AddValue(key string, val interface{}) {
+	if obj, ok := val.(ObjectMarshaler); ok && !isNilValue(obj) {
+		e.AddObject(key, obj)
+		return
+	}
+	e.key(key)
+	writeJSONPair(&e.buf, toPair(key, val))
+}
+
+func (e *objectEncoder) AddObject(key string, obj ObjectMarshaler) {
+	e.key(key)
+	e.buf.WriteRune('{')
+	e.comma = false
+	obj.MarshalKiwi(e)
+	e.buf.WriteRune('}')
+	e.comma = true
+}
@@ -0,0 +1,152 @@
+package kiwi
+
+/*
+Copyright (c) 2016-2018, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व
+
+All tests consists of three parts:
+
+- arrange structures and initialize objects for use in tests
+- act on testing object
+- check and assert on results
+
+These parts separated by empty lines in each test function.
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+type marshalerUser struct {
+	name  string
+	admin bool
+}
+
+func (u marshalerUser) MarshalKiwi(enc PairEncoder) {
+	enc.AddString("name", u.name)
+	enc.AddBool("admin", u.admin)
+}
+
+type marshalerDelayed struct{}
+
+func (marshalerDelayed) MarshalKiwi(enc PairEncoder) {
+	enc.AddValue("n", func() int { return 1 })
+}
+
+type marshalerRequest struct {
+	method string
+	user   marshalerUser
+}
+
+func (r marshalerRequest) MarshalKiwi(enc PairEncoder) {
+	enc.AddString("method", r.method)
+	enc.AddInt("size", 42)
+	enc.AddObject("user", r.user)
+}
+
+// Test of the object expanded to the pairs with prefixed keys.
+func TestMarshaler_Log_Logfmt(t *testing.T) {
+	output := bytes.NewBufferString("")
+	log := New()
+	out := SinkTo(output, AsLogfmt()).Start()
+
+	log.Log("req", marshalerRequest{"GET", marshalerUser{"alice", true}})
+
+	out.Close()
+	if strings.TrimSpace(output.String()) != `req.method="GET" req.size=42 req.user.name="alice" req.user.admin=true` {
+		println(output.String())
+		t.Fail()
+	}
+}
+
+// Test of the object in the context of the logger. The pairs of the
+// object should replace the pairs with the same keys.
+func TestMarshaler_With_Logfmt(t *testing.T) {
+	output := bytes.NewBufferString("")
+	log := New()
+	out := SinkTo(output, AsLogfmt()).Start()
+
+	log.With("user", marshalerUser{"alice", false}).With("user", marshalerUser{"bob", true})
+	log.Add("k", 1).Log()
+
+	out.Close()
+	if strings.TrimSpace(output.String()) != `user.name="bob" user.admin=true k=1` {
+		println(output.String())
+		t.Fail()
+	}
+}
+
+// Test of the object converted to the single pair. It should be
+// the nested JSON object.
+func TestMarshaler_Pair_JSON(t *testing.T) {
+	p := toPair("req", marshalerRequest{"GET", marshalerUser{"alice", true}})
+
+	if p.Type != ObjectVal || p.Val != `{"method":"GET","size":42,"user":{"name":"alice","admin":true}}` {
+		println(p.Val)
+		t.Fail()
+	}
+}
+
+// Test of the nil pointer to the object. It should be logged as nil
+// value instead of calling the marshaler with nil receiver.
+func TestMarshaler_NilPointer_Logfmt(t *testing.T) {
+	output := bytes.NewBufferString("")
+	log := New()
+	out := SinkTo(output, AsLogfmt()).Start()
+	var user *marshalerUser
+
+	log.With("c", user).Add("a", user).Log("u", user)
+
+	out.Close()
+	if strings.TrimSpace(output.String()) != `c="<nil>" a="<nil>" u="<nil>"` {
+		println(output.String())
+		t.Fail()
+	}
+}
+
+// Test of the delayed values nested in the structured value. They
+// should be evaluated and the value should be valid JSON.
+func TestMarshaler_NestedDelayed_JSON(t *testing.T) {
+	var v struct {
+		O struct{ N int }
+		F string
+	}
+
+	p := toPair("m", map[string]interface{}{"O": marshalerDelayed{}, "F": func() string { return "x" }})
+	err := json.Unmarshal([]byte(p.Val), &v)
+
+	if err != nil || v.O.N != 1 || v.F != "x" {
+		println(p.Val)
+		t.Fail()
+	}
+}
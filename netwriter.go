@@ -0,0 +1,246 @@
+package kiwi
+
+// This file consists of the network writer that reconnects on failures.
+
+/* Copyright (c) 2016-2020, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व */
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Default settings of NetWriter.
+var (
+	NetMinBackoff  = 100 * time.Millisecond
+	NetMaxBackoff  = 30 * time.Second
+	NetQueueSize   = 1024
+	NetDialTimeout = 5 * time.Second
+)
+
+// ErrWriterClosed returned by the writers that already closed.
+var ErrWriterClosed = errors.New("writer closed")
+
+// ErrNotConnected returned by NetWriter without the queue while it
+// is disconnected.
+var ErrNotConnected = errors.New("writer not connected")
+
+// NetWriter writes the records to TCP or Unix stream socket. It dials
+// lazily on the first write and reconnects with exponential backoff
+// when the write fails. While it is disconnected the records are kept
+// in the bounded queue and sent in the same order after the
+// connection restored. If the queue is full the oldest records are
+// dropped. NetWriter is safe for concurrent usage.
+type NetWriter struct {
+	network    string
+	addr       string
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	queueSize  int
+	connected  int32
+	dropped    uint64
+
+	sync.Mutex
+	conn         net.Conn
+	queue        [][]byte
+	reconnecting bool
+	closed       bool
+	done         chan struct{}
+}
+
+// DialNet creates a new writer for the network ("tcp", "tcp4", "tcp6"
+// or "unix") and the address. The connection is not established until
+// the first write.
+func DialNet(network, addr string) *NetWriter {
+	return &NetWriter{
+		network:    network,
+		addr:       addr,
+		minBackoff: NetMinBackoff,
+		maxBackoff: NetMaxBackoff,
+		queueSize:  NetQueueSize,
+		done:       make(chan struct{}),
+	}
+}
+
+// SinkToNet creates a new sink that writes the records to the network
+// with NetWriter. The connection state of the writer is reported by
+// Sink.Connected(). As for SinkTo() the sink should be started
+// explicitly. The connection is closed when the sink closed.
+func SinkToNet(network, addr string, fn Formatter) *Sink {
+	var (
+		w    = DialNet(network, addr)
+		sink = SinkTo(w, fn)
+	)
+	sink.closer = w
+	return sink
+}
+
+// Backoff sets minimal and maximal delays between reconnects.
+func (w *NetWriter) Backoff(min, max time.Duration) *NetWriter {
+	w.Lock()
+	w.minBackoff, w.maxBackoff = min, max
+	w.Unlock()
+	return w
+}
+
+// QueueSize sets maximum number of the records kept while the writer
+// is disconnected. Zero size disables the queue: the writes return
+// ErrNotConnected until the connection restored. It is useful when
+// the writer wrapped with Spool().
+func (w *NetWriter) QueueSize(size int) *NetWriter {
+	w.Lock()
+	w.queueSize = size
+	w.Unlock()
+	return w
+}
+
+// Connected reports whether the writer has established connection.
+func (w *NetWriter) Connected() bool {
+	return atomic.LoadInt32(&w.connected) == 1
+}
+
+// Queued returns the number of the records waiting for the connection.
+func (w *NetWriter) Queued() int {
+	w.Lock()
+	defer w.Unlock()
+	return len(w.queue)
+}
+
+// Dropped returns the number of the records dropped because of queue
+// overflow.
+func (w *NetWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+// Write sends the record to the connection. If the writer is not
+// connected the record is queued and the error is not returned.
+func (w *NetWriter) Write(p []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+	if w.closed {
+		return 0, ErrWriterClosed
+	}
+	if w.conn != nil {
+		if _, err := w.conn.Write(p); err == nil {
+			return len(p), nil
+		}
+		w.conn.Close()
+		w.conn = nil
+		atomic.StoreInt32(&w.connected, 0)
+	}
+	if !w.reconnecting {
+		w.reconnecting = true
+		go w.reconnect()
+	}
+	if w.queueSize <= 0 {
+		return 0, ErrNotConnected
+	}
+	w.enqueue(p)
+	return len(p), nil
+}
+
+// Close closes the connection. The queued records are discarded.
+func (w *NetWriter) Close() error {
+	w.Lock()
+	defer w.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	close(w.done)
+	w.queue = nil
+	atomic.StoreInt32(&w.connected, 0)
+	if w.conn != nil {
+		return w.conn.Close()
+	}
+	return nil
+}
+
+func (w *NetWriter) enqueue(p []byte) {
+	if len(w.queue) >= w.queueSize {
+		copy(w.queue, w.queue[1:])
+		w.queue = w.queue[:len(w.queue)-1]
+		atomic.AddUint64(&w.dropped, 1)
+	}
+	w.queue = append(w.queue, append([]byte(nil), p...))
+}
+
+func (w *NetWriter) reconnect() {
+	w.Lock()
+	var backoff = w.minBackoff
+	w.Unlock()
+	for {
+		conn, err := net.DialTimeout(w.network, w.addr, NetDialTimeout)
+		w.Lock()
+		if w.closed {
+			w.reconnecting = false
+			w.Unlock()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+		if err == nil {
+			if err = w.flush(conn); err == nil {
+				w.conn = conn
+				w.reconnecting = false
+				atomic.StoreInt32(&w.connected, 1)
+				w.Unlock()
+				return
+			}
+			conn.Close()
+		}
+		var max = w.maxBackoff
+		w.Unlock()
+		select {
+		case <-time.After(backoff):
+		case <-w.done:
+		}
+		if backoff *= 2; backoff > max {
+			backoff = max
+		}
+	}
+}
+
+// flush sends the queued records. The records are removed from the
+// queue only after successful write.
+func (w *NetWriter) flush(conn net.Conn) error {
+	for len(w.queue) > 0 {
+		if _, err := conn.Write(w.queue[0]); err != nil {
+			return err
+		}
+		w.queue[0] = nil
+		w.queue = w.queue[1:]
+	}
+	return nil
+}
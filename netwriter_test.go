@@ -0,0 +1,125 @@
+package kiwi
+
+/*
+Copyright (c) 2016-2018, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व
+
+All tests consists of three parts:
+
+- arrange structures and initialize objects for use in tests
+- act on testing object
+- check and assert on results
+
+These parts separated by empty lines in each test function.
+*/
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+)
+
+// Test of the sink to the network. The records should be delivered
+// after the lazy connect and after the reconnect.
+func TestNetWriter_Reconnect(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	log := New()
+	out := SinkToNet("tcp", l.Addr().String(), AsLogfmt()).Start()
+	defer out.Close()
+	out.writer.(*NetWriter).Backoff(10*time.Millisecond, 50*time.Millisecond)
+
+	if out.Connected() {
+		t.Fatal("should not be connected before the first write")
+	}
+	log.Log("n", 1)
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	if line, err := bufio.NewReader(conn).ReadString('\n'); err != nil || line != "n=1 \n" {
+		t.Fatalf("unexpected line %q: %v", line, err)
+	}
+	conn.Close()
+	accepted := make(chan net.Conn)
+	go func() {
+		c, _ := l.Accept()
+		accepted <- c
+	}()
+	var second net.Conn
+	for i := 2; second == nil; i++ {
+		log.Log("n", i)
+		select {
+		case second = <-accepted:
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	defer second.Close()
+
+	second.SetReadDeadline(time.Now().Add(3 * time.Second))
+	if _, err = bufio.NewReader(second).ReadString('\n'); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(3 * time.Second); !out.Connected(); {
+		if time.Now().After(deadline) {
+			t.Fatal("should be connected after reconnect")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Test of the queue of disconnected writer. The oldest records should
+// be dropped on overflow.
+func TestNetWriter_Queue(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	w := DialNet("tcp", addr).Backoff(time.Hour, time.Hour).QueueSize(2)
+	defer w.Close()
+
+	w.Write([]byte("1"))
+	w.Write([]byte("2"))
+	w.Write([]byte("3"))
+
+	if w.Connected() || w.Queued() != 2 || w.Dropped() != 1 {
+		t.Errorf("unexpected state: connected=%v queued=%d dropped=%d", w.Connected(), w.Queued(), w.Dropped())
+	}
+	if string(w.queue[0]) != "2" || string(w.queue[1]) != "3" {
+		t.Errorf("unexpected queue %q", w.queue)
+	}
+}
@@ -0,0 +1,287 @@
+package kiwi
+
+// This file consists of the conversion of the structured values (maps,
+// slices and structs) to JSON.
+
+/* Copyright (c) 2016-2020, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व */
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxObjectDepth limits the nesting of the structured values. The
+// values nested deeper replaced with "..." string.
+var MaxObjectDepth = 8
+
+// The placeholders for the values that could not be walked.
+const (
+	objectTooDeep = "..."
+	objectCycle   = "<cycle>"
+)
+
+// isObject reports whether the value is the map, the slice, the array
+// or the struct (or the pointer to any of them) so it should be
+// logged as the structured value.
+func isObject(val interface{}) bool {
+	var v = reflect.ValueOf(val)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		return true
+	}
+	return false
+}
+
+// objectToJSON converts the structured value to JSON. The exported
+// fields of the structs are output with their names or the names
+// from `json` tags. The keys of the maps are sorted.
+func objectToJSON(val interface{}) string {
+	var w = objectWriter{seen: make(map[uintptr]bool)}
+	w.write(reflect.ValueOf(val), 0)
+	return w.buf.String()
+}
+
+type objectWriter struct {
+	buf  bytes.Buffer
+	seen map[uintptr]bool
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	marshalerType     = reflect.TypeOf((*ObjectMarshaler)(nil)).Elem()
+	errorType         = reflect.TypeOf((*error)(nil)).Elem()
+	valuerType        = reflect.TypeOf((*Valuer)(nil)).Elem()
+	stringerType      = reflect.TypeOf((*Stringer)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func (w *objectWriter) write(v reflect.Value, depth int) {
+	if !v.IsValid() {
+		w.buf.WriteString("null")
+		return
+	}
+	if depth > MaxObjectDepth {
+		writeJSONString(&w.buf, objectTooDeep)
+		return
+	}
+	// The interfaces (the fields or the elements of interface
+	// types) checked by the value they hold.
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			w.buf.WriteString("null")
+			return
+		}
+		v = v.Elem()
+	}
+	// The types that know how to represent themselves converted
+	// the same way as the top level values.
+	if v.CanInterface() && (v.Kind() != reflect.Ptr || !v.IsNil()) && (v.Type() == timeType ||
+		converterOf(v.Type()) != nil ||
+		v.Type().Implements(marshalerType) || v.Type().Implements(errorType) || v.Type().Implements(valuerType) ||
+		v.Type().Implements(stringerType) || v.Type().Implements(textMarshalerType)) {
+		writeJSONPair(&w.buf, toPair("", v.Interface()))
+		return
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			w.buf.WriteString("null")
+			return
+		}
+		if w.seen[v.Pointer()] {
+			writeJSONString(&w.buf, objectCycle)
+			return
+		}
+		w.seen[v.Pointer()] = true
+		defer delete(w.seen, v.Pointer())
+		w.write(v.Elem(), depth)
+	case reflect.Struct:
+		w.writeStruct(v, depth)
+	case reflect.Map:
+		if v.IsNil() {
+			w.buf.WriteString("null")
+			return
+		}
+		if w.seen[v.Pointer()] {
+			writeJSONString(&w.buf, objectCycle)
+			return
+		}
+		w.seen[v.Pointer()] = true
+		defer delete(w.seen, v.Pointer())
+		w.writeMap(v, depth)
+	case reflect.Slice:
+		if v.IsNil() {
+			w.buf.WriteString("null")
+			return
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			writeJSONString(&w.buf, string(v.Bytes()))
+			return
+		}
+		w.writeArray(v, depth)
+	case reflect.Array:
+		w.writeArray(v, depth)
+	case reflect.Bool:
+		w.buf.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		w.buf.WriteString(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		w.buf.WriteString(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		writeJSONFloat(&w.buf, v.Float())
+	case reflect.String:
+		writeJSONString(&w.buf, v.String())
+	case reflect.Func:
+		if v.IsNil() {
+			w.buf.WriteString("null")
+			return
+		}
+		if v.CanInterface() {
+			// The delayed values evaluated, the other
+			// functions output as strings.
+			writeJSONPair(&w.buf, toPair("", v.Interface()))
+			return
+		}
+		writeJSONString(&w.buf, fmt.Sprintf("%v", v))
+	default:
+		// Channels, functions and complex numbers have no
+		// JSON representation.
+		writeJSONString(&w.buf, fmt.Sprintf("%v", v))
+	}
+}
+
+func (w *objectWriter) writeStruct(v reflect.Value, depth int) {
+	var (
+		t     = v.Type()
+		comma bool
+	)
+	w.buf.WriteRune('{')
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			if tag == "-" {
+				continue
+			}
+			if n := strings.Split(tag, ",")[0]; n != "" {
+				name = n
+			}
+		}
+		if comma {
+			w.buf.WriteRune(',')
+		}
+		comma = true
+		writeJSONString(&w.buf, name)
+		w.buf.WriteRune(':')
+		w.write(v.Field(i), depth+1)
+	}
+	w.buf.WriteRune('}')
+}
+
+func (w *objectWriter) writeMap(v reflect.Value, depth int) {
+	var keys = make([]string, 0, v.Len())
+	var vals = make(map[string]reflect.Value, v.Len())
+	for _, k := range v.MapKeys() {
+		key := fmt.Sprint(k.Interface())
+		keys = append(keys, key)
+		vals[key] = v.MapIndex(k)
+	}
+	sort.Strings(keys)
+	w.buf.WriteRune('{')
+	for i, key := range keys {
+		if i > 0 {
+			w.buf.WriteRune(',')
+		}
+		writeJSONString(&w.buf, key)
+		w.buf.WriteRune(':')
+		w.write(vals[key], depth+1)
+	}
+	w.buf.WriteRune('}')
+}
+
+func (w *objectWriter) writeArray(v reflect.Value, depth int) {
+	w.buf.WriteRune('[')
+	for i := 0; i < v.Len(); i++ {
+		if i > 0 {
+			w.buf.WriteRune(',')
+		}
+		w.write(v.Index(i), depth+1)
+	}
+	w.buf.WriteRune(']')
+}
+
+// writeJSONFloat outputs the float. JSON has no representation for
+// NaN and infinities so they are output as strings.
+func writeJSONFloat(buf *bytes.Buffer, v float64) {
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		writeJSONString(buf, strconv.FormatFloat(v, 'g', -1, 64))
+		return
+	}
+	buf.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+}
+
+// writeJSONPair outputs the value converted by toPair() accordingly
+// with its type.
+func writeJSONPair(buf *bytes.Buffer, p *Pair) {
+	// The nested delayed values are evaluated at once because
+	// the record evaluates only its own pairs.
+	if p.Eval != nil {
+		p = evalPair(p)
+	}
+	switch p.Type {
+	case BooleanVal, IntegerVal, ErrorVal, ObjectVal:
+		buf.WriteString(p.Val)
+	case FloatVal:
+		if v, err := strconv.ParseFloat(p.Val, 64); err == nil {
+			writeJSONFloat(buf, v)
+			return
+		}
+		writeJSONString(buf, p.Val)
+	default:
+		writeJSONString(buf, p.Val)
+	}
+}
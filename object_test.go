@@ -0,0 +1,138 @@
+package kiwi
+
+/*
+Copyright (c) 2016-2018, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व
+
+All tests consists of three parts:
+
+- arrange structures and initialize objects for use in tests
+- act on testing object
+- check and assert on results
+
+These parts separated by empty lines in each test function.
+*/
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type objectRequest struct {
+	Method  string
+	Headers map[string]string `json:"headers"`
+	Sizes   []int             `json:"sizes"`
+	Secret  string            `json:"-"`
+	private int
+}
+
+type objectNode struct {
+	Name string
+	Next *objectNode
+}
+
+// Test of the structured value in logfmt. It should be flattened to
+// the pairs with dotted keys.
+func TestObject_Flatten_Logfmt(t *testing.T) {
+	output := bytes.NewBufferString("")
+	log := New()
+	out := SinkTo(output, AsLogfmt()).Start()
+	req := objectRequest{Method: "GET", Headers: map[string]string{"host": "example.com"}, Sizes: []int{1, 2}, Secret: "x"}
+
+	log.Log("req", req)
+
+	out.Close()
+	if strings.TrimSpace(output.String()) != `req.Method="GET" req.headers.host="example.com" req.sizes.0=1 req.sizes.1=2` {
+		println(output.String())
+		t.Fail()
+	}
+}
+
+// Test of the broken structured value in logfmt. It should be output
+// as the single string without the pairs flattened before the error.
+func TestObject_FlattenBroken_Logfmt(t *testing.T) {
+	f := AsLogfmt()
+
+	f.Begin()
+	f.Pair("a", "1", IntegerVal)
+	f.Pair("req", `{"n":1,"m":`, ObjectVal)
+	line := string(f.Finish())
+
+	if strings.TrimSpace(line) != `a=1 req="{\"n\":1,\"m\":"` {
+		println(line)
+		t.Fail()
+	}
+}
+
+// Test of the structured value in JSON. It should be output as the
+// nested object.
+func TestObject_Nested_JSON(t *testing.T) {
+	output := bytes.NewBufferString("")
+	log := New()
+	out := SinkTo(output, AsJSON()).Start()
+
+	log.Log("m", map[string]interface{}{"b": []string{"x"}, "a": 1.5})
+
+	out.Close()
+	if !strings.Contains(output.String(), `"m":{"a":1.5,"b":["x"]}`) {
+		println(output.String())
+		t.Fail()
+	}
+}
+
+// Test of the cyclic structure. The cycle should be replaced with the
+// placeholder instead of endless recursion.
+func TestObject_Cycle(t *testing.T) {
+	node := &objectNode{Name: "a"}
+	node.Next = node
+
+	result := objectToJSON(node)
+
+	if result != `{"Name":"a","Next":"<cycle>"}` {
+		println(result)
+		t.Fail()
+	}
+}
+
+// Test of the depth limit. The values nested deeper than the limit
+// should be replaced with the placeholder.
+func TestObject_MaxDepth(t *testing.T) {
+	original := MaxObjectDepth
+	MaxObjectDepth = 1
+
+	result := objectToJSON([][][]int{{{1}}})
+
+	MaxObjectDepth = original
+	if result != `[["..."]]` {
+		println(result)
+		t.Fail()
+	}
+}
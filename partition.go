@@ -0,0 +1,245 @@
+package kiwi
+
+// This file consists of the writer that writes the records to the files partitioned by a key.
+
+/* Copyright (c) 2016-2020, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व */
+
+import (
+	"container/list"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Default settings of the partitioned writer.
+var (
+	PartitionMaxOpen  = 64
+	PartitionFallback = "default"
+)
+
+// PartitionWriter routes the records to the files named after the
+// value of the key. The files are opened lazily and the number of
+// the opened files is limited: the least recently used file closed
+// when the limit reached. Optionally the files are rotated by size.
+// It is safe for concurrent usage.
+type PartitionWriter struct {
+	sync.Mutex
+	template    string
+	placeholder string
+	key         string
+	maxOpen     int
+	rotateSize  int64
+	rotateKeep  int
+	files       map[string]*list.Element
+	lru         *list.List
+}
+
+type partitionFile struct {
+	path string
+	file *os.File
+	size int64
+}
+
+// SinkToPartitioned creates a new sink that writes the records to
+// the files partitioned by the value of the key. The path template
+// should contain the key in braces that replaced by the value:
+//
+//	kiwi.SinkToPartitioned("logs/{tenant}.log", "tenant", kiwi.AsLogfmt())
+//
+// The records without the key written to PartitionFallback
+// partition. Use NewPartitionWriter() with SinkTo() for changing
+// the limit of the opened files or for the rotation of the files.
+// As for SinkTo() the sink should be started explicitly. The files
+// are closed when the sink closed.
+func SinkToPartitioned(pathTemplate, key string, fn Formatter) *Sink {
+	var (
+		w    = NewPartitionWriter(pathTemplate, key)
+		sink = SinkTo(w, fn)
+	)
+	sink.closer = w
+	return sink
+}
+
+// NewPartitionWriter creates the writer for the path template and the
+// key. See SinkToPartitioned() for details.
+func NewPartitionWriter(pathTemplate, key string) *PartitionWriter {
+	return &PartitionWriter{
+		template:    pathTemplate,
+		placeholder: "{" + key + "}",
+		key:         key,
+		maxOpen:     PartitionMaxOpen,
+		files:       make(map[string]*list.Element),
+		lru:         list.New(),
+	}
+}
+
+// MaxOpen sets the limit of the simultaneously opened files.
+func (w *PartitionWriter) MaxOpen(n int) *PartitionWriter {
+	w.Lock()
+	if n > 0 {
+		w.maxOpen = n
+	}
+	w.Unlock()
+	return w
+}
+
+// Rotate enables the rotation of the files by size. When the file
+// exceeds maxBytes it renamed with ".1" suffix, previous rotated
+// files are shifted and only keep files are kept.
+func (w *PartitionWriter) Rotate(maxBytes int64, keep int) *PartitionWriter {
+	w.Lock()
+	w.rotateSize = maxBytes
+	w.rotateKeep = keep
+	w.Unlock()
+	return w
+}
+
+// Write writes the record to the fallback partition.
+func (w *PartitionWriter) Write(p []byte) (int, error) {
+	return w.writeTo(PartitionFallback, p)
+}
+
+func (w *PartitionWriter) writeRecord(record []*Pair, data []byte) (int, error) {
+	var partition = PartitionFallback
+	for _, p := range record {
+		if p.Key == w.key {
+			partition = p.Val
+		}
+	}
+	return w.writeTo(partition, data)
+}
+
+// Close closes all the opened files.
+func (w *PartitionWriter) Close() error {
+	w.Lock()
+	defer w.Unlock()
+	var err error
+	for e := w.lru.Front(); e != nil; e = e.Next() {
+		if cerr := e.Value.(*partitionFile).file.Close(); cerr != nil {
+			err = cerr
+		}
+	}
+	w.files = make(map[string]*list.Element)
+	w.lru.Init()
+	return err
+}
+
+func (w *PartitionWriter) writeTo(partition string, p []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+	var path = strings.Replace(w.template, w.placeholder, partitionName(partition), -1)
+	f, err := w.open(path)
+	if err != nil {
+		return 0, err
+	}
+	if w.rotateSize > 0 && f.size > 0 && f.size+int64(len(p)) > w.rotateSize {
+		if err = w.rotate(f); err != nil {
+			w.lru.Remove(w.files[path])
+			delete(w.files, path)
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// open returns the opened file for the path. It should be called
+// under the lock.
+func (w *PartitionWriter) open(path string) (*partitionFile, error) {
+	if e, ok := w.files[path]; ok {
+		w.lru.MoveToFront(e)
+		return e.Value.(*partitionFile), nil
+	}
+	for w.lru.Len() >= w.maxOpen {
+		var (
+			e = w.lru.Back()
+			f = e.Value.(*partitionFile)
+		)
+		f.file.Close()
+		delete(w.files, f.path)
+		w.lru.Remove(e)
+	}
+	var f = &partitionFile{path: path}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	w.files[path] = w.lru.PushFront(f)
+	return f, nil
+}
+
+func (f *partitionFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0750); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+	fi, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = fi.Size()
+	return nil
+}
+
+// rotate shifts the rotated files and reopens the file.
+func (w *PartitionWriter) rotate(f *partitionFile) error {
+	f.file.Close()
+	if w.rotateKeep > 0 {
+		for i := w.rotateKeep - 1; i > 0; i-- {
+			os.Rename(f.path+"."+strconv.Itoa(i), f.path+"."+strconv.Itoa(i+1))
+		}
+		os.Rename(f.path, f.path+".1")
+	} else {
+		os.Remove(f.path)
+	}
+	return f.open()
+}
+
+// partitionName makes the value safe for the usage in the file name.
+func partitionName(val string) string {
+	if val == "" || val == "." || val == ".." {
+		return "_"
+	}
+	var name = []byte(val)
+	for i, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '-' || c == '_') {
+			name[i] = '_'
+		}
+	}
+	return string(name)
+}
@@ -0,0 +1,111 @@
+package kiwi
+
+/*
+Copyright (c) 2016-2018, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व
+
+All tests consists of three parts:
+
+- arrange structures and initialize objects for use in tests
+- act on testing object
+- check and assert on results
+
+These parts separated by empty lines in each test function.
+*/
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Test of the partitioned sink. The records should be written to the
+// files named after the values of the key.
+func TestPartition_Sink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kiwi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	log := New()
+	w := NewPartitionWriter(filepath.Join(dir, "{tenant}", "app.log"), "tenant").MaxOpen(1)
+	out := SinkTo(w, AsLogfmt()).Start()
+
+	log.Log("tenant", "alpha", "n", 1)
+	log.Log("tenant", "beta", "n", 2)
+	log.Log("tenant", "alpha", "n", 3)
+	log.Log("tenant", "../etc", "n", 4)
+	log.Log("n", 5)
+
+	out.Close()
+	w.Close()
+	expected := map[string]string{
+		"alpha/app.log":   "tenant=\"alpha\" n=1 \ntenant=\"alpha\" n=3 \n",
+		"beta/app.log":    "tenant=\"beta\" n=2 \n",
+		".._etc/app.log":  "tenant=\"../etc\" n=4 \n",
+		"default/app.log": "n=5 \n",
+	}
+	for name, content := range expected {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(data) != content {
+			t.Errorf("unexpected content of %s: %q %v", name, data, err)
+		}
+	}
+	if w.lru.Len() != 0 {
+		t.Errorf("files should be closed")
+	}
+}
+
+// Test of the rotation of the partitioned files.
+func TestPartition_Rotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kiwi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	w := NewPartitionWriter(filepath.Join(dir, "{k}.log"), "k").Rotate(10, 2)
+	defer w.Close()
+
+	for _, rec := range []string{"1234567\n", "abcdefg\n", "ABCDEFG\n", "last\n"} {
+		w.writeRecord([]*Pair{{Key: "k", Val: "p"}}, []byte(rec))
+	}
+
+	expected := map[string]string{"p.log": "last\n", "p.log.1": "ABCDEFG\n", "p.log.2": "abcdefg\n"}
+	for name, content := range expected {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(data) != content {
+			t.Errorf("unexpected content of %s: %q %v", name, data, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "p.log.3")); !os.IsNotExist(err) {
+		t.Errorf("only two rotated files should be kept")
+	}
+}
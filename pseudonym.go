@@ -0,0 +1,139 @@
+package kiwi
+
+// This file consists of the pseudonymization of the values by HMAC.
+
+/* Copyright (c) 2016-2020, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व */
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync/atomic"
+)
+
+// pseudonymSize is the number of the bytes of HMAC digest kept in the
+// pseudonym.
+const pseudonymSize = 16
+
+// Pseudonymize replaces the values of the keys with HMAC-SHA256
+// digests keyed by the secret. The same value always gets the same
+// pseudonym within the sink so the records could be correlated (for
+// example the records of the same user) but the original value
+// could not be restored without the secret. The keys matched the
+// same way as for Redact(): case insensitive with the whole key, its
+// last part after the dot and the keys of the nested objects. The
+// secret should be kept out of the logs. Next call adds the keys and
+// replaces the secret (see RotateSecret()). It panics if the secret
+// is empty because the digests without the secret could be reversed
+// by the dictionary.
+func (s *Sink) Pseudonymize(secret []byte, keys ...string) *Sink {
+	checkPseudonymSecret(secret)
+	if atomic.LoadInt32(s.state) > sinkClosed {
+		s.Lock()
+		if s.pseudoKeys == nil {
+			s.pseudoKeys = make(map[string]bool)
+		}
+		for _, key := range keys {
+			s.pseudoKeys[strings.ToLower(key)] = true
+		}
+		s.pseudoSecret = append([]byte(nil), secret...)
+		s.Unlock()
+	}
+	return s
+}
+
+// RotateSecret replaces the secret of the pseudonymization. The
+// records written after the rotation get other pseudonyms for the
+// same values so they could not be correlated with the records
+// written before it. It panics if the secret is empty.
+func (s *Sink) RotateSecret(secret []byte) *Sink {
+	checkPseudonymSecret(secret)
+	if atomic.LoadInt32(s.state) > sinkClosed {
+		s.Lock()
+		s.pseudoSecret = append([]byte(nil), secret...)
+		s.Unlock()
+	}
+	return s
+}
+
+func checkPseudonymSecret(secret []byte) {
+	if len(secret) == 0 {
+		panic("kiwi: empty secret for the pseudonymization")
+	}
+}
+
+// pseudonymize returns the pair with the pseudonym instead of the
+// value if the key is selected for the pseudonymization. The values
+// of the keys nested in the structured values and the errors
+// replaced too.
+func (s *Sink) pseudonymize(pair *Pair) *Pair {
+	if len(s.pseudoKeys) == 0 {
+		return pair
+	}
+	if s.pseudoKey(pair.Key) {
+		return &Pair{Key: pair.Key, Val: pseudonym(s.pseudoSecret, pair.Val), Type: StringVal}
+	}
+	if pair.Type != ErrorVal && pair.Type != ObjectVal {
+		return pair
+	}
+	var w = jsonRewriter{
+		member: func(key string, raw []byte) (string, bool, bool) {
+			if !s.pseudoKey(key) {
+				return "", false, false
+			}
+			return pseudonym(s.pseudoSecret, jsonText(raw)), false, true
+		},
+	}
+	if val, changed := w.rewrite(pair.Val); changed {
+		return &Pair{Key: pair.Key, Val: val, Type: pair.Type}
+	}
+	return pair
+}
+
+// pseudoKey reports whether the key is selected for the
+// pseudonymization.
+func (s *Sink) pseudoKey(key string) bool {
+	var lower = strings.ToLower(key)
+	if s.pseudoKeys[lower] {
+		return true
+	}
+	if i := strings.LastIndexByte(lower, '.'); i >= 0 {
+		return s.pseudoKeys[lower[i+1:]]
+	}
+	return false
+}
+
+func pseudonym(secret []byte, val string) string {
+	var mac = hmac.New(sha256.New, secret)
+	mac.Write([]byte(val))
+	return hex.EncodeToString(mac.Sum(nil)[:pseudonymSize])
+}
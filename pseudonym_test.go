@@ -0,0 +1,126 @@
+package kiwi
+
+/*
+Copyright (c) 2016-2018, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व
+
+All tests consists of three parts:
+
+- arrange structures and initialize objects for use in tests
+- act on testing object
+- check and assert on results
+
+These parts separated by empty lines in each test function.
+*/
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// Test of the pseudonymization. The same values should get the same
+// pseudonyms and the raw values should not be output.
+func TestPseudonym_SameValues_Logfmt(t *testing.T) {
+	output := bytes.NewBufferString("")
+	log := New()
+	out := SinkTo(output, AsLogfmt()).Pseudonymize([]byte("secret"), "email").Start()
+
+	log.Log("email", "alice@example.com", "n", 1)
+	log.Log("email", "alice@example.com", "n", 2)
+	log.Log("email", "bob@example.com", "n", 3)
+
+	out.Close()
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	token := pseudonym([]byte("secret"), "alice@example.com")
+	if len(lines) != 3 || strings.Contains(output.String(), "@example.com") ||
+		strings.TrimSpace(lines[0]) != `email="`+token+`" n=1` ||
+		strings.TrimSpace(lines[1]) != `email="`+token+`" n=2` ||
+		strings.Contains(lines[2], token) {
+		println(output.String())
+		t.Fail()
+	}
+}
+
+// Test of the rotation of the secret. The same value should get the
+// other pseudonym after the rotation.
+func TestPseudonym_RotateSecret_Logfmt(t *testing.T) {
+	output := bytes.NewBufferString("")
+	log := New()
+	out := SinkTo(output, AsLogfmt()).Pseudonymize([]byte("old"), "user").Start()
+
+	log.Log("user", 42)
+	out.RotateSecret([]byte("new"))
+	log.Log("user", 42)
+
+	out.Close()
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 2 ||
+		strings.TrimSpace(lines[0]) != `user="`+pseudonym([]byte("old"), "42")+`"` ||
+		strings.TrimSpace(lines[1]) != `user="`+pseudonym([]byte("new"), "42")+`"` {
+		println(output.String())
+		t.Fail()
+	}
+}
+
+// Test of the pseudonymization of the keys nested in the structured
+// values and in the pairs made by Struct(). The nested value should
+// get the same pseudonym as the top level one.
+func TestPseudonym_Nested_JSON(t *testing.T) {
+	output := bytes.NewBufferString("")
+	log := New()
+	out := SinkTo(output, AsJSON()).Pseudonymize([]byte("secret"), "email").Start()
+	type user struct{ Email string }
+
+	log.Log("req", map[string]interface{}{"email": "alice@example.com", "n": 1})
+	log.Log(Struct("user", user{"alice@example.com"}))
+
+	out.Close()
+	token := pseudonym([]byte("secret"), "alice@example.com")
+	if strings.Contains(output.String(), "@example.com") ||
+		!strings.Contains(output.String(), `"req":{"email":"`+token+`","n":1}`) ||
+		!strings.Contains(output.String(), `"user.Email":"`+token+`"`) {
+		println(output.String())
+		t.Fail()
+	}
+}
+
+// Test of the empty secret. It should be rejected.
+func TestPseudonym_EmptySecret(t *testing.T) {
+	out := SinkTo(bytes.NewBufferString(""), AsLogfmt())
+	defer out.Close()
+	defer func() {
+		if recover() == nil {
+			t.Fail()
+		}
+	}()
+
+	out.Pseudonymize(nil, "email")
+}
@@ -0,0 +1,359 @@
+package kiwi
+
+// This file consists of the secret values and the redaction of the pairs.
+
+/* Copyright (c) 2016-2020, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व */
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// RedactStrategy defines how the sink redacts the pairs.
+type RedactStrategy int
+
+// Strategies of the redaction.
+const (
+	// RedactMask replaces the value with SecretMask.
+	RedactMask RedactStrategy = iota
+	// RedactHash replaces the value with the hex of the first 8
+	// bytes of its HMAC-SHA256 digest. The key of HMAC is random
+	// and created for each sink so the same values get the same
+	// hashes while the sink lives. Use Pseudonymize() for the
+	// hashes that should be the same after restarts.
+	RedactHash
+	// RedactDrop removes the pair from the record.
+	RedactDrop
+)
+
+// secretValue masks the wrapped value in any output.
+type secretValue struct {
+	val interface{}
+}
+
+// Secret wraps the value so it always rendered as SecretMask. It
+// protects the tokens and the passwords from occasional logging:
+//
+//	log.Log("token", kiwi.Secret(token))
+func Secret(v interface{}) Valuer {
+	return secretValue{v}
+}
+
+func (secretValue) String() string   { return SecretMask }
+func (secretValue) GoString() string { return SecretMask }
+func (secretValue) IsQuoted() bool   { return true }
+
+type redactPattern struct {
+	re       *regexp.Regexp
+	strategy RedactStrategy
+}
+
+// Redact sets the strategy of the redaction for the values of the
+// keys. The keys compared case insensitive with the whole key of the
+// pair, with its last part after the dot (so "password" matches
+// "user.Password" made by Struct()) and with the keys of the objects
+// nested in the structured values and the errors. The redaction is
+// made before formatting so it is the same for all formatters and
+// for the writers that use the pairs (for example for Loki labels).
+// The filters of the sink check the original values. Reset() does
+// not remove the redaction.
+func (s *Sink) Redact(strategy RedactStrategy, keys ...string) *Sink {
+	if atomic.LoadInt32(s.state) > sinkClosed {
+		s.Lock()
+		if s.redactKeys == nil {
+			s.redactKeys = make(map[string]RedactStrategy)
+		}
+		for _, key := range keys {
+			s.redactKeys[strings.ToLower(key)] = strategy
+		}
+		s.initRedactHash(strategy)
+		s.Unlock()
+	}
+	return s
+}
+
+// RedactMatch sets the strategy of the redaction for the pairs
+// matched by the regular expression. When the key (or the key of the
+// nested object) matched the whole value is redacted. When the parts
+// of the string value (or of the string nested in the structured
+// value) matched only they are masked or hashed. For RedactDrop the
+// pair (or the nested value) is dropped.
+//
+//	sink.RedactMatch(kiwi.RedactMask, regexp.MustCompile(`(?i)passw|token|Bearer \S+`))
+func (s *Sink) RedactMatch(strategy RedactStrategy, re *regexp.Regexp) *Sink {
+	if atomic.LoadInt32(s.state) > sinkClosed {
+		s.Lock()
+		s.redactPatterns = append(s.redactPatterns, redactPattern{re, strategy})
+		s.initRedactHash(strategy)
+		s.Unlock()
+	}
+	return s
+}
+
+// initRedactHash creates the random key for RedactHash. The sink
+// should be locked.
+func (s *Sink) initRedactHash(strategy RedactStrategy) {
+	if strategy != RedactHash || s.redactHashKey != nil {
+		return
+	}
+	s.redactHashKey = make([]byte, 32)
+	if _, err := rand.Read(s.redactHashKey); err != nil {
+		panic("kiwi: could not create the key for RedactHash: " + err.Error())
+	}
+}
+
+// redact returns the pair with the redacted value. The original
+// pair is returned if it should not be redacted and nil returned
+// if the pair should be dropped.
+func (s *Sink) redact(pair *Pair) *Pair {
+	if len(s.redactKeys) == 0 && len(s.redactPatterns) == 0 {
+		return pair
+	}
+	if strategy, ok := s.redactKey(pair.Key); ok {
+		if strategy == RedactDrop {
+			return nil
+		}
+		return &Pair{Key: pair.Key, Val: s.redactValue(pair.Val, strategy), Type: StringVal}
+	}
+	if pair.Type == ErrorVal || pair.Type == ObjectVal {
+		// The structured values redacted by their nested keys
+		// and strings so they are kept valid JSON.
+		var w = jsonRewriter{
+			member: func(key string, raw []byte) (string, bool, bool) {
+				strategy, ok := s.redactKey(key)
+				if !ok {
+					return "", false, false
+				}
+				if strategy == RedactDrop {
+					return "", true, true
+				}
+				return s.redactValue(jsonText(raw), strategy), false, true
+			},
+			leaf: s.redactString,
+		}
+		if val, changed := w.rewrite(pair.Val); changed {
+			return &Pair{Key: pair.Key, Val: val, Type: pair.Type}
+		}
+		return pair
+	}
+	val, drop, changed := s.redactString(pair.Val)
+	switch {
+	case drop:
+		return nil
+	case changed:
+		return &Pair{Key: pair.Key, Val: val, Type: StringVal}
+	}
+	return pair
+}
+
+// redactKey returns the strategy of the redaction for the key.
+func (s *Sink) redactKey(key string) (RedactStrategy, bool) {
+	var lower = strings.ToLower(key)
+	if strategy, ok := s.redactKeys[lower]; ok {
+		return strategy, true
+	}
+	if i := strings.LastIndexByte(lower, '.'); i >= 0 {
+		if strategy, ok := s.redactKeys[lower[i+1:]]; ok {
+			return strategy, true
+		}
+	}
+	for _, p := range s.redactPatterns {
+		if p.re.MatchString(key) {
+			return p.strategy, true
+		}
+	}
+	return 0, false
+}
+
+// redactString redacts the parts of the string matched by the
+// patterns.
+func (s *Sink) redactString(val string) (result string, drop, changed bool) {
+	for _, p := range s.redactPatterns {
+		if !p.re.MatchString(val) {
+			continue
+		}
+		if p.strategy == RedactDrop {
+			return "", true, true
+		}
+		var strategy = p.strategy
+		val = p.re.ReplaceAllStringFunc(val, func(v string) string {
+			return s.redactValue(v, strategy)
+		})
+		changed = true
+	}
+	return val, false, changed
+}
+
+func (s *Sink) redactValue(val string, strategy RedactStrategy) string {
+	if strategy == RedactHash {
+		var mac = hmac.New(sha256.New, s.redactHashKey)
+		mac.Write([]byte(val))
+		return hex.EncodeToString(mac.Sum(nil)[:8])
+	}
+	return SecretMask
+}
+
+// jsonRewriter rebuilds JSON value with the values of the object
+// members replaced or dropped by their keys and the strings replaced
+// or dropped by their values. The order of the members is kept.
+type jsonRewriter struct {
+	// member returns the replacement for the raw value of the
+	// object member or the drop flag. It returns false if the
+	// member should be kept.
+	member func(key string, raw []byte) (val string, drop, ok bool)
+	// leaf returns the replacement of the string value.
+	leaf func(val string) (result string, drop, changed bool)
+}
+
+// rewrite returns the rebuilt value and true if anything changed.
+// The value returned as is if it is not valid JSON.
+func (w *jsonRewriter) rewrite(data string) (string, bool) {
+	val, drop, changed := w.value([]byte(data))
+	if drop || !changed {
+		return data, false
+	}
+	return val, true
+}
+
+// value returns the rewritten raw JSON value.
+func (w *jsonRewriter) value(raw []byte) (val string, drop, changed bool) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return "", false, false
+	}
+	switch raw[0] {
+	case '"':
+		s, ok := stringOf(raw)
+		if !ok || w.leaf == nil {
+			break
+		}
+		res, drop, changed := w.leaf(s)
+		if !changed || drop {
+			return string(raw), drop, changed
+		}
+		var buf bytes.Buffer
+		writeJSONString(&buf, res)
+		return buf.String(), false, true
+	case '{', '[':
+		return w.container(raw)
+	}
+	return string(raw), false, false
+}
+
+func (w *jsonRewriter) container(raw []byte) (string, bool, bool) {
+	var (
+		dec            = json.NewDecoder(bytes.NewReader(raw))
+		buf            bytes.Buffer
+		comma, changed bool
+	)
+	tok, err := dec.Token()
+	if err != nil {
+		return string(raw), false, false
+	}
+	var isObject = tok == json.Delim('{')
+	buf.WriteByte(raw[0])
+	for dec.More() {
+		var (
+			key  string
+			item json.RawMessage
+		)
+		if isObject {
+			if tok, err = dec.Token(); err != nil {
+				return string(raw), false, false
+			}
+			key = tok.(string)
+		}
+		if err = dec.Decode(&item); err != nil {
+			return string(raw), false, false
+		}
+		var (
+			val      string
+			drop, ok bool
+		)
+		if isObject && w.member != nil {
+			if val, drop, ok = w.member(key, item); ok && !drop {
+				var quoted bytes.Buffer
+				writeJSONString(&quoted, val)
+				val = quoted.String()
+			}
+		}
+		if !ok {
+			val, drop, ok = w.value(item)
+		}
+		changed = changed || ok
+		if drop {
+			continue
+		}
+		if comma {
+			buf.WriteRune(',')
+		}
+		comma = true
+		if isObject {
+			writeJSONString(&buf, key)
+			buf.WriteRune(':')
+		}
+		buf.WriteString(val)
+	}
+	if !changed {
+		return string(raw), false, false
+	}
+	if isObject {
+		buf.WriteRune('}')
+	} else {
+		buf.WriteRune(']')
+	}
+	return buf.String(), false, true
+}
+
+// stringOf returns the string if the raw JSON value is the string.
+func stringOf(raw []byte) (string, bool) {
+	var s string
+	if len(raw) == 0 || raw[0] != '"' || json.Unmarshal(raw, &s) != nil {
+		return "", false
+	}
+	return s, true
+}
+
+// jsonText returns the string for the raw JSON string value or the
+// raw JSON for other values.
+func jsonText(raw []byte) string {
+	if s, ok := stringOf(raw); ok {
+		return s
+	}
+	return string(raw)
+}
@@ -0,0 +1,148 @@
+package kiwi
+
+/*
+Copyright (c) 2016-2018, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व
+
+All tests consists of three parts:
+
+- arrange structures and initialize objects for use in tests
+- act on testing object
+- check and assert on results
+
+These parts separated by empty lines in each test function.
+*/
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// Test of the secret value. It should be masked in any format.
+func TestRedact_Secret(t *testing.T) {
+	output := bytes.NewBufferString("")
+	log := New()
+	out := SinkTo(output, AsJSON()).Start()
+
+	log.Log("token", Secret("qwerty"), "nested", map[string]interface{}{"pass": Secret(42)})
+
+	out.Close()
+	if strings.Contains(output.String(), "qwerty") || strings.Contains(output.String(), "42") ||
+		fmt.Sprintf("%v %#v", Secret("qwerty"), Secret("qwerty")) != SecretMask+" "+SecretMask {
+		println(output.String())
+		t.Fail()
+	}
+}
+
+// Test of the redaction of the keys with the different strategies.
+func TestRedact_Keys_Logfmt(t *testing.T) {
+	output := bytes.NewBufferString("")
+	log := New()
+	out := SinkTo(output, AsLogfmt()).
+		Redact(RedactMask, "password").
+		Redact(RedactHash, "email").
+		Redact(RedactDrop, "card").Start()
+
+	log.Log("user", "alice", "password", "qwerty", "email", "alice@example.com", "card", 4111)
+	log.Log("email", "alice@example.com")
+
+	out.Close()
+	hash := out.redactValue("alice@example.com", RedactHash)
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(hash) != 16 || hash == fmt.Sprintf("%x", sha256.Sum256([]byte("alice@example.com")))[:16] ||
+		len(lines) != 2 ||
+		strings.TrimSpace(lines[0]) != `user="alice" password="******" email="`+hash+`"` ||
+		strings.TrimSpace(lines[1]) != `email="`+hash+`"` {
+		println(output.String())
+		t.Fail()
+	}
+}
+
+// Test of the redaction by the regular expression. The matched keys
+// should be redacted entirely and the matched parts of the values
+// should be masked.
+func TestRedact_Match_Logfmt(t *testing.T) {
+	output := bytes.NewBufferString("")
+	log := New()
+	out := SinkTo(output, AsLogfmt()).RedactMatch(RedactMask, regexp.MustCompile(`(?i)token|Bearer \S+`)).Start()
+
+	log.Log("access-token", "abc", "header", "Authorization: Bearer abc123", "k", 1)
+
+	out.Close()
+	if strings.TrimSpace(output.String()) != `access-token="******" header="Authorization: ******" k=1` {
+		println(output.String())
+		t.Fail()
+	}
+}
+
+// Test of the redaction of the keys nested in the structured values
+// and in the pairs made by Struct(). The result should be valid JSON.
+func TestRedact_NestedKeys_JSON(t *testing.T) {
+	output := bytes.NewBufferString("")
+	log := New()
+	out := SinkTo(output, AsJSON()).Redact(RedactMask, "password").Redact(RedactDrop, "card").Start()
+	type user struct {
+		Name     string
+		Password string
+	}
+
+	log.Log("req", map[string]interface{}{"password": "hunter2", "card": 4111, "user": "alice"})
+	log.Log(Struct("user", user{"bob", "qwerty"}))
+
+	out.Close()
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 2 || strings.Contains(output.String(), "hunter2") || strings.Contains(output.String(), "qwerty") ||
+		!strings.Contains(lines[0], `"req":{"password":"******","user":"alice"}`) ||
+		!strings.Contains(lines[1], `"user.Password":"******"`) {
+		println(output.String())
+		t.Fail()
+	}
+}
+
+// Test of the value patterns applied to the strings nested in the
+// structured value. The value should stay valid JSON with all its
+// fields.
+func TestRedact_MatchNested_JSON(t *testing.T) {
+	output := bytes.NewBufferString("")
+	log := New()
+	out := SinkTo(output, AsJSON()).RedactMatch(RedactMask, regexp.MustCompile(`Bearer \S+`)).Start()
+
+	log.Log("req", map[string]interface{}{"auth": "Bearer abc123", "list": []string{"Bearer x", "y"}, "n": 1})
+
+	out.Close()
+	if !strings.Contains(output.String(), `"req":{"auth":"******","list":["******","y"],"n":1}`) {
+		println(output.String())
+		t.Fail()
+	}
+}
@@ -0,0 +1,80 @@
+package kiwi
+
+// Mapping of the level names to the severities of logging protocols.
+
+/* Copyright (c) 2016-2020, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व */
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Severities defined by RFC-5424 (syslog). They are also used by GELF
+// and journald.
+const (
+	sevEmergency = iota
+	sevAlert
+	sevCritical
+	sevError
+	sevWarning
+	sevNotice
+	sevInfo
+	sevDebug
+)
+
+// syslogSeverity converts the value of level to the syslog
+// severity. The names used by the level package and the common
+// abbreviations are recognized. Also numeric values 0..7 accepted
+// as is. Unknown levels treated as informational.
+func syslogSeverity(level string) int {
+	switch strings.ToLower(level) {
+	case "emerg", "emergency", "panic":
+		return sevEmergency
+	case "alert":
+		return sevAlert
+	case "fatal", "crit", "critical":
+		return sevCritical
+	case "err", "error":
+		return sevError
+	case "warn", "warning":
+		return sevWarning
+	case "notice":
+		return sevNotice
+	case "info", "informational":
+		return sevInfo
+	case "debug", "trace":
+		return sevDebug
+	}
+	if n, err := strconv.Atoi(level); err == nil && n >= sevEmergency && n <= sevDebug {
+		return n
+	}
+	return sevInfo
+}
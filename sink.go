@@ -36,6 +36,7 @@ OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 
 import (
 	"io"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -48,6 +49,10 @@ const (
 	sinkActive
 )
 
+// activeSinks counts the started sinks so the loggers could skip
+// building of the records when nobody accepts them.
+var activeSinks int32
+
 // Sinks accepts records through the chanels.
 // Each sink has its own channel.
 var collector struct {
@@ -65,6 +70,23 @@ type (
 		writer io.Writer
 		format Formatter
 		state  *int32
+		// closer is set for the writers created by the sink
+		// itself so the sink is responsible for closing them.
+		closer io.Closer
+		// breaker keeps *circuitBreaker if it set for the sink.
+		breaker atomic.Value
+		// floatFormat and timeLayout override the global
+		// FloatFormat and TimeLayout when they set.
+		floatFormat byte
+		timeLayout  string
+		// redactKeys and redactPatterns set by Redact() and
+		// RedactMatch().
+		redactKeys     map[string]RedactStrategy
+		redactPatterns []redactPattern
+		redactHashKey  []byte
+		// pseudoKeys and pseudoSecret set by Pseudonymize().
+		pseudoKeys   map[string]bool
+		pseudoSecret []byte
 
 		sync.RWMutex
 		positiveFilters map[string]Filter
@@ -74,9 +96,21 @@ type (
 	chain struct {
 		wg    *sync.WaitGroup
 		pairs []*Pair
+		check int
 	}
 )
 
+// The pairs of the record checked by the filters of the sink.
+const (
+	checkAll = iota
+	// checkResolved checks the pairs without the delayed values
+	// before the evaluation.
+	checkResolved
+	// checkDelayed checks the evaluated delayed values of the
+	// record that already passed checkResolved.
+	checkDelayed
+)
+
 // SinkTo creates a new sink for an arbitrary number of loggers.
 // There are any number of sinks may be created for saving incoming log
 // records to different places.
@@ -248,6 +282,31 @@ func (s *Sink) WithFilter(key string, customFilter Filter) *Sink {
 	return s
 }
 
+// FloatFormat sets the format of the float values for the sink. It
+// overrides the global FloatFormat for the values logged with their
+// original types (see Pair.Raw). The format is the same as the second
+// parameter of strconv.FormatFloat().
+func (s *Sink) FloatFormat(format byte) *Sink {
+	if atomic.LoadInt32(s.state) > sinkClosed {
+		s.Lock()
+		s.floatFormat = format
+		s.Unlock()
+	}
+	return s
+}
+
+// TimeLayout sets the layout of the time values for the sink. It
+// overrides the global TimeLayout for the values logged with their
+// original types (see Pair.Raw).
+func (s *Sink) TimeLayout(layout string) *Sink {
+	if atomic.LoadInt32(s.state) > sinkClosed {
+		s.Lock()
+		s.timeLayout = layout
+		s.Unlock()
+	}
+	return s
+}
+
 // Reset all filters for the keys for the output. If no one key
 // provided it do global reset for all filters of the sink.
 func (s *Sink) Reset(keys ...string) *Sink {
@@ -295,7 +354,9 @@ func (s *Sink) Unhide(keys ...string) *Sink {
 
 // Stop stops writing to the output.
 func (s *Sink) Stop() *Sink {
-	atomic.StoreInt32(s.state, sinkStopped)
+	if atomic.CompareAndSwapInt32(s.state, sinkActive, sinkStopped) {
+		atomic.AddInt32(&activeSinks, -1)
+	}
 	return s
 }
 
@@ -303,14 +364,28 @@ func (s *Sink) Stop() *Sink {
 // After creation of a new sink it will paused and you need explicitly start it.
 // It allows setup the filters before the sink will accepts any records.
 func (s *Sink) Start() *Sink {
-	atomic.StoreInt32(s.state, sinkActive)
+	if atomic.CompareAndSwapInt32(s.state, sinkStopped, sinkActive) {
+		atomic.AddInt32(&activeSinks, 1)
+	}
 	return s
 }
 
+// Connected reports the state of the connection for the sinks that
+// write to the network (see SinkToNet()). For other writers it always
+// returns true.
+func (s *Sink) Connected() bool {
+	if c, ok := s.writer.(interface{ Connected() bool }); ok {
+		return c.Connected()
+	}
+	return true
+}
+
 // Close closes the sink. It flushes records for the sink before closing.
 func (s *Sink) Close() {
 	if atomic.LoadInt32(s.state) > sinkClosed {
-		atomic.StoreInt32(s.state, sinkClosed)
+		if atomic.SwapInt32(s.state, sinkClosed) == sinkActive {
+			atomic.AddInt32(&activeSinks, -1)
+		}
 		s.close <- struct{}{}
 		collector.Lock()
 		for i, v := range collector.sinks {
@@ -322,6 +397,9 @@ func (s *Sink) Close() {
 			}
 		}
 		collector.Unlock()
+		if s.closer != nil {
+			s.closer.Close()
+		}
 	}
 }
 
@@ -341,23 +419,9 @@ func processSink(s *Sink) {
 				continue
 			}
 			s.RLock()
-			var filter Filter
-			for _, pair := range record.pairs {
-				// Negative conditions have highest priority
-				if filter, ok = s.negativeFilters[pair.Key]; ok {
-					if filter.Check(pair.Key, pair.Val) {
-						goto skipRecord
-					}
-				}
-				// At last check for positive conditions
-				if filter, ok = s.positiveFilters[pair.Key]; ok {
-					if !filter.Check(pair.Key, pair.Val) {
-						goto skipRecord
-					}
-				}
+			if s.accepts(record.pairs, record.check) {
+				s.formatRecord(record.pairs)
 			}
-			s.formatRecord(record.pairs)
-		skipRecord:
 			s.RUnlock()
 			record.wg.Done()
 		case <-s.close:
@@ -371,26 +435,130 @@ func processSink(s *Sink) {
 	}
 }
 
+// accepts checks the pairs of the record selected by check with the
+// filters of the sink. The sink should be read locked.
+func (s *Sink) accepts(record []*Pair, check int) bool {
+	for _, pair := range record {
+		if check == checkResolved && pair.Eval != nil || check == checkDelayed && pair.Eval == nil {
+			continue
+		}
+		// Negative conditions have highest priority
+		if filter, ok := s.negativeFilters[pair.Key]; ok {
+			if checkFilter(filter, pair) {
+				return false
+			}
+		}
+		// At last check for positive conditions
+		if filter, ok := s.positiveFilters[pair.Key]; ok {
+			if !checkFilter(filter, pair) {
+				return false
+			}
+		}
+	}
+	if b := s.getBreaker(); b != nil && !b.ready() {
+		return false
+	}
+	return true
+}
+
+// recordWriter realized by the writers that need the pairs of the
+// record along with its formatted representation. For example for
+// selecting the labels or the partition by the values.
+type recordWriter interface {
+	writeRecord(record []*Pair, data []byte) (int, error)
+}
+
 func (s *Sink) formatRecord(record []*Pair) {
+	var (
+		_, isRecordWriter = s.writer.(recordWriter)
+		typed, isTyped    = s.format.(TypedFormatter)
+		visible           []*Pair
+	)
 	s.format.Begin()
 	for _, pair := range record {
 		if ok := s.hiddenKeys[pair.Key]; ok {
 			continue
 		}
-		s.format.Pair(pair.Key, pair.Val, pair.Type)
+		if pair = s.redact(pair); pair == nil {
+			continue
+		}
+		pair = s.pseudonymize(pair)
+		if isRecordWriter {
+			visible = append(visible, pair)
+		}
+		if isTyped {
+			typed.TypedPair(pair.Key, s.formatValue(pair), pair.Raw, pair.Type)
+		} else {
+			s.format.Pair(pair.Key, s.formatValue(pair), pair.Type)
+		}
+	}
+	if b := s.getBreaker(); b != nil {
+		b.write(s, func(data []byte) error { return s.write(visible, data) }, s.format.Finish())
+		return
+	}
+	s.write(visible, s.format.Finish())
+}
+
+// formatValue returns the string representation of the value
+// accordingly with the float format and the time layout of the sink.
+func (s *Sink) formatValue(pair *Pair) string {
+	if s.floatFormat == 0 && s.timeLayout == "" || pair.Raw == nil {
+		return pair.Val
+	}
+	switch v := pair.Raw.(type) {
+	case float64:
+		if s.floatFormat != 0 {
+			return strconv.FormatFloat(v, s.floatFormat, -1, 64)
+		}
+	case float32:
+		if s.floatFormat != 0 {
+			return strconv.FormatFloat(float64(v), s.floatFormat, -1, 32)
+		}
+	case time.Time:
+		if s.timeLayout != "" && pair.Type == TimeVal {
+			return v.Format(s.timeLayout)
+		}
+	}
+	return pair.Val
+}
+
+// write passes the formatted record to the writer.
+func (s *Sink) write(record []*Pair, data []byte) error {
+	var err error
+	if rw, ok := s.writer.(recordWriter); ok {
+		_, err = rw.writeRecord(record, data)
+	} else {
+		_, err = s.writer.Write(data)
 	}
-	s.writer.Write(s.format.Finish())
+	return err
 }
 
 const flushTimeout = 3 * time.Second
 
-func sinkRecord(rec []*Pair) {
-	var wg sync.WaitGroup
+// sinkRecord passes the record to the active sinks. If the sinks
+// that accepted the record before evaluation of its delayed values
+// are given then only they get it and check only the delayed values.
+func sinkRecord(rec []*Pair, accepted []*Sink) {
+	var (
+		wg    sync.WaitGroup
+		check = checkAll
+	)
+	if accepted != nil {
+		check = checkDelayed
+	}
 	collector.RLock()
 	for _, s := range collector.sinks {
 		if atomic.LoadInt32(s.state) == sinkActive {
+			if accepted != nil && !hasSink(accepted, s) {
+				continue
+			}
+			// The sink with opened circuit breaker skipped
+			// until the time of the probe.
+			if b := s.getBreaker(); b != nil && !b.ready() {
+				continue
+			}
 			wg.Add(1)
-			s.In <- chain{&wg, rec}
+			s.In <- chain{&wg, rec, check}
 		}
 	}
 	collector.RUnlock()
@@ -421,3 +421,67 @@ func TestSink_WithCustomFilterOut(t *testing.T) {
 		t.Fail()
 	}
 }
+
+// Test of the float format and the time layout of the sink. They
+// should override the global ones for the typed values.
+func TestSink_FloatFormatTimeLayout_Logfmt(t *testing.T) {
+	stream := bytes.NewBufferString("")
+	log := New()
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	out := SinkTo(stream, AsLogfmt()).FloatFormat('f').TimeLayout("2006-01-02").Start()
+
+	log.Log("pi", 3.14, "at", at, "s", "3.14")
+
+	out.Close()
+	if strings.TrimSpace(stream.String()) != `pi=3.14 at=2020-01-02 s="3.14"` {
+		println(stream.String())
+		t.Fail()
+	}
+}
+
+// Test of the time range filter with the typed value. The value that
+// differs from the bound by less than a second should be checked
+// without the precision lost on the formatting.
+func TestSink_TimeRangeTyped_Logfmt(t *testing.T) {
+	stream := bytes.NewBufferString("")
+	log := New()
+	from := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	out := SinkTo(stream, AsLogfmt()).TimeRange("at", from, from.Add(time.Second)).Start()
+
+	log.Log("at", from.Add(time.Millisecond), "n", 1)
+	log.Log("at", from, "n", 2)
+
+	out.Close()
+	if !strings.Contains(stream.String(), "n=1") || strings.Contains(stream.String(), "n=2") {
+		println(stream.String())
+		t.Fail()
+	}
+}
+
+type customTypedFilter struct{}
+
+func (customTypedFilter) Check(key, val string) bool {
+	return false
+}
+
+func (customTypedFilter) CheckTyped(key, val string, raw interface{}) bool {
+	_, ok := raw.(int)
+	return ok
+}
+
+// Test of the custom filter with the typed check. The record should
+// be passed by the original type of the value.
+func TestSink_WithTypedFilter(t *testing.T) {
+	stream := bytes.NewBufferString("")
+	log := New()
+	out := SinkTo(stream, AsLogfmt()).WithFilter("key", customTypedFilter{}).Start()
+
+	log.Log("key", 2)
+	log.Log("key", "2")
+
+	out.Close()
+	if strings.TrimSpace(stream.String()) != `key=2` {
+		println(stream.String())
+		t.Fail()
+	}
+}
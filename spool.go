@@ -0,0 +1,363 @@
+package kiwi
+
+// This file consists of the disk spool for the writers of the sinks.
+
+/* Copyright (c) 2016-2020, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व */
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Default settings of the spool.
+var (
+	SpoolSegmentSize   int64 = 1 << 20
+	SpoolRetryInterval       = time.Second
+)
+
+const (
+	spoolExt       = ".spool"
+	spoolRecordHdr = 4
+)
+
+// SpoolWriter wraps the writer of a sink and keeps the records on
+// local disk while the writer fails. The records appended to the
+// segment files in the directory until the total size reaches the
+// cap, the records that exceed the cap are dropped. The spooled
+// records are replayed in the same order when the writer recovers.
+// While the spool is not empty the new records are spooled too so
+// the order is kept. The records left from the previous run are
+// replayed as well.
+//
+// The writer considered failed when its Write() returns an error. So
+// the writers that have own queues should be configured to return
+// the errors (see NetWriter.QueueSize()).
+type SpoolWriter struct {
+	w        io.Writer
+	dir      string
+	maxBytes int64
+	dropped  uint64
+	done     chan struct{}
+	stopped  sync.WaitGroup
+
+	sync.Mutex
+	segments []uint64
+	out      *os.File
+	outSize  int64
+	in       *os.File
+	inOffset int64
+	size     int64
+	depth    int
+	closed   bool
+	hdr      [spoolRecordHdr]byte
+	rec      []byte
+}
+
+// Spool creates the spool for the writer in the directory. The
+// directory created if it not exists. The maxBytes is the cap for the
+// total size of the spooled records.
+//
+//	spool, err := kiwi.Spool(kiwi.DialNet("tcp", addr).QueueSize(0), "/var/spool/app", 1<<30)
+//	kiwi.SinkTo(spool, kiwi.AsJSON()).Start()
+func Spool(w io.Writer, dir string, maxBytes int64) (*SpoolWriter, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, err
+	}
+	var s = &SpoolWriter{w: w, dir: dir, maxBytes: maxBytes, done: make(chan struct{})}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	s.stopped.Add(1)
+	go s.loop(SpoolRetryInterval)
+	return s, nil
+}
+
+// Depth returns the number of the records in the spool.
+func (s *SpoolWriter) Depth() int {
+	s.Lock()
+	defer s.Unlock()
+	return s.depth
+}
+
+// Size returns the total size of the spooled records in bytes.
+func (s *SpoolWriter) Size() int64 {
+	s.Lock()
+	defer s.Unlock()
+	return s.size
+}
+
+// Dropped returns the number of the records that not fit the cap of
+// the spool.
+func (s *SpoolWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Write passes the record to the writer or spools it if the writer
+// failed or the spool is not empty yet.
+func (s *SpoolWriter) Write(p []byte) (int, error) {
+	s.Lock()
+	defer s.Unlock()
+	if s.closed {
+		return 0, ErrWriterClosed
+	}
+	if s.depth == 0 {
+		if _, err := s.w.Write(p); err == nil {
+			return len(p), nil
+		}
+	}
+	if err := s.append(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Replay tries to pass the spooled records to the writer. It is
+// called periodically by the spool but may be called explicitly.
+func (s *SpoolWriter) Replay() error {
+	s.Lock()
+	defer s.Unlock()
+	return s.replay()
+}
+
+// Close stops the replays and closes the segment files. The spooled
+// records are kept on the disk for the next run.
+func (s *SpoolWriter) Close() error {
+	s.Lock()
+	if s.closed {
+		s.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+	s.Unlock()
+	s.stopped.Wait()
+	s.Lock()
+	defer s.Unlock()
+	if s.in != nil && s.in != s.out {
+		s.in.Close()
+	}
+	s.in = nil
+	if s.out != nil {
+		s.out.Close()
+		s.out = nil
+	}
+	return nil
+}
+
+func (s *SpoolWriter) loop(interval time.Duration) {
+	defer s.stopped.Done()
+	var ticker = time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.Replay()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *SpoolWriter) segmentPath(id uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d%s", id, spoolExt))
+}
+
+// load finds the segments left from the previous run and counts the
+// records in them.
+func (s *SpoolWriter) load() error {
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	for _, fi := range files {
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), spoolExt) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(fi.Name(), spoolExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		s.segments = append(s.segments, id)
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i] < s.segments[j] })
+	for _, id := range s.segments {
+		f, err := os.Open(s.segmentPath(id))
+		if err != nil {
+			return err
+		}
+		var off int64
+		for {
+			size, err := s.readRecordSize(f, off)
+			if err != nil {
+				break
+			}
+			off += spoolRecordHdr + size
+			s.depth++
+		}
+		s.size += off
+		f.Close()
+	}
+	return nil
+}
+
+// append writes the record to the last segment. It should be called
+// under the lock.
+func (s *SpoolWriter) append(p []byte) error {
+	var size = int64(spoolRecordHdr + len(p))
+	if s.size+size > s.maxBytes {
+		atomic.AddUint64(&s.dropped, 1)
+		return nil
+	}
+	if s.out == nil || s.outSize+size > SpoolSegmentSize && s.outSize > 0 {
+		if err := s.nextSegment(); err != nil {
+			return err
+		}
+	}
+	binary.BigEndian.PutUint32(s.hdr[:], uint32(len(p)))
+	if _, err := s.out.Write(s.hdr[:]); err != nil {
+		return err
+	}
+	if _, err := s.out.Write(p); err != nil {
+		return err
+	}
+	s.outSize += size
+	s.size += size
+	s.depth++
+	return nil
+}
+
+func (s *SpoolWriter) nextSegment() error {
+	var id uint64
+	if len(s.segments) > 0 {
+		id = s.segments[len(s.segments)-1] + 1
+	}
+	f, err := os.OpenFile(s.segmentPath(id), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+	if s.out != nil && s.out != s.in {
+		s.out.Close()
+	}
+	s.out = f
+	s.outSize = 0
+	s.segments = append(s.segments, id)
+	return nil
+}
+
+// replay passes the records from the first segment to the writer
+// until the writer fails. It should be called under the lock.
+func (s *SpoolWriter) replay() error {
+	for s.depth > 0 {
+		if s.in == nil {
+			f, err := os.Open(s.segmentPath(s.segments[0]))
+			if err != nil {
+				return err
+			}
+			s.in = f
+			s.inOffset = 0
+		}
+		size, err := s.readRecordSize(s.in, s.inOffset)
+		if err == nil {
+			if cap(s.rec) < int(size) {
+				s.rec = make([]byte, size)
+			}
+			s.rec = s.rec[:size]
+			_, err = s.in.ReadAt(s.rec, s.inOffset+spoolRecordHdr)
+		}
+		if err != nil {
+			// The rest of the segment is unreadable or the
+			// segment is over.
+			if len(s.segments) == 1 {
+				s.reset()
+				return nil
+			}
+			s.dropSegment()
+			continue
+		}
+		if _, err = s.w.Write(s.rec); err != nil {
+			return err
+		}
+		s.inOffset += spoolRecordHdr + size
+		s.size -= spoolRecordHdr + size
+		s.depth--
+	}
+	if len(s.segments) > 0 {
+		s.reset()
+	}
+	return nil
+}
+
+func (s *SpoolWriter) readRecordSize(f *os.File, off int64) (int64, error) {
+	if _, err := f.ReadAt(s.hdr[:], off); err != nil {
+		return 0, err
+	}
+	var size = int64(binary.BigEndian.Uint32(s.hdr[:]))
+	if fi, err := f.Stat(); err != nil || off+spoolRecordHdr+size > fi.Size() {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return size, nil
+}
+
+// dropSegment removes the first segment that already replayed.
+func (s *SpoolWriter) dropSegment() {
+	s.in.Close()
+	s.in = nil
+	os.Remove(s.segmentPath(s.segments[0]))
+	s.segments = s.segments[1:]
+}
+
+// reset removes all the segments when the spool became empty.
+func (s *SpoolWriter) reset() {
+	if s.in != nil && s.in != s.out {
+		s.in.Close()
+	}
+	if s.out != nil {
+		s.out.Close()
+	}
+	s.in, s.out = nil, nil
+	s.outSize = 0
+	for _, id := range s.segments {
+		os.Remove(s.segmentPath(id))
+	}
+	s.segments = nil
+	s.size = 0
+	s.depth = 0
+}
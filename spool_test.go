@@ -0,0 +1,154 @@
+package kiwi
+
+/*
+Copyright (c) 2016-2018, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व
+
+All tests consists of three parts:
+
+- arrange structures and initialize objects for use in tests
+- act on testing object
+- check and assert on results
+
+These parts separated by empty lines in each test function.
+*/
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+type flakyWriter struct {
+	sync.Mutex
+	fail    bool
+	records []string
+}
+
+func (w *flakyWriter) Write(p []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+	if w.fail {
+		return 0, errors.New("failed")
+	}
+	w.records = append(w.records, string(p))
+	return len(p), nil
+}
+
+func (w *flakyWriter) setFail(fail bool) {
+	w.Lock()
+	w.fail = fail
+	w.Unlock()
+}
+
+// Test of the spool. The records should be kept on the disk while the
+// writer fails and replayed in order after it recovered.
+func TestSpool_Replay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kiwi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	original := SpoolSegmentSize
+	SpoolSegmentSize = 10
+	defer func() { SpoolSegmentSize = original }()
+	w := &flakyWriter{fail: true}
+	s, err := Spool(w, dir, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	s.Write([]byte("first"))
+	w.setFail(false)
+	s.Write([]byte("second"))
+	s.Write([]byte("third"))
+
+	if s.Depth() != 3 || len(w.records) != 0 {
+		t.Fatalf("unexpected depth %d, records %q", s.Depth(), w.records)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.spool")); len(files) != 3 {
+		t.Errorf("unexpected segments %v", files)
+	}
+	if err = s.Replay(); err != nil {
+		t.Fatal(err)
+	}
+	if s.Depth() != 0 || s.Size() != 0 || len(w.records) != 3 ||
+		w.records[0] != "first" || w.records[1] != "second" || w.records[2] != "third" {
+		t.Errorf("unexpected depth %d, records %q", s.Depth(), w.records)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.spool")); len(files) != 0 {
+		t.Errorf("unexpected segments %v", files)
+	}
+	s.Write([]byte("fourth"))
+	if s.Depth() != 0 || len(w.records) != 4 {
+		t.Errorf("unexpected depth %d, records %q", s.Depth(), w.records)
+	}
+}
+
+// Test of the spool left from the previous run and of the size cap.
+func TestSpool_Reload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kiwi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	w := &flakyWriter{fail: true}
+	s, err := Spool(w, dir, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Write([]byte("first"))
+	s.Write([]byte("second"))
+	s.Write([]byte("third"))
+	s.Close()
+
+	if s.Dropped() != 1 {
+		t.Errorf("unexpected dropped %d", s.Dropped())
+	}
+	w.setFail(false)
+	s, err = Spool(w, dir, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.Depth() != 2 || s.Size() != 19 {
+		t.Errorf("unexpected depth %d and size %d", s.Depth(), s.Size())
+	}
+	s.Write([]byte("fourth"))
+	s.Replay()
+	if len(w.records) != 3 || w.records[0] != "first" || w.records[2] != "fourth" {
+		t.Errorf("unexpected records %q", w.records)
+	}
+}
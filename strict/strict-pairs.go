@@ -44,13 +44,13 @@ import (
 // String formats pair for string.
 // Note: type helpers are experimental part of API and may be removed.
 func String(key string, val string) *kiwi.Pair {
-	return &kiwi.Pair{key, val, nil, kiwi.StringVal}
+	return &kiwi.Pair{Key: key, Val: val, Type: kiwi.StringVal, Raw: val}
 }
 
 // Stringer formats pair for string.
 // Note: type helpers are experimental part of API and may be removed.
 func Stringer(key string, val kiwi.Stringer) *kiwi.Pair {
-	return &kiwi.Pair{key, val.String(), nil, kiwi.StringVal}
+	return &kiwi.Pair{Key: key, Val: val.String(), Type: kiwi.StringVal, Raw: val}
 }
 
 // Int formats pair for int value. If you need add integer of specific size just
@@ -58,39 +58,39 @@ func Stringer(key string, val kiwi.Stringer) *kiwi.Pair {
 // respectively.
 // Note: type helpers are experimental part of API and may be removed.
 func Int(key string, val int) *kiwi.Pair {
-	return &kiwi.Pair{key, strconv.Itoa(val), nil, kiwi.IntegerVal}
+	return &kiwi.Pair{Key: key, Val: strconv.Itoa(val), Type: kiwi.IntegerVal, Raw: val}
 }
 
 // Int64 formats pair for int64 value.
 // Note: type helpers are experimental part of API and may be removed.
 func Int64(key string, val int64) *kiwi.Pair {
-	return &kiwi.Pair{key, strconv.FormatInt(val, 10), nil, kiwi.IntegerVal}
+	return &kiwi.Pair{Key: key, Val: strconv.FormatInt(val, 10), Type: kiwi.IntegerVal, Raw: val}
 }
 
 // Uint64 formats pair for uint64 value.
 // Note: type helpers are experimental part of API and may be removed.
 func Uint64(key string, val uint64) *kiwi.Pair {
-	return &kiwi.Pair{key, strconv.FormatUint(val, 10), nil, kiwi.IntegerVal}
+	return &kiwi.Pair{Key: key, Val: strconv.FormatUint(val, 10), Type: kiwi.IntegerVal, Raw: val}
 }
 
 // Float64 formats pair for float64 value. If you need add float of other size just
 // convert it to float64.
 // Note: type helpers are experimental part of API and may be removed.
 func Float64(key string, val float64) *kiwi.Pair {
-	return &kiwi.Pair{key, strconv.FormatFloat(val, 'e', -1, 64), nil, kiwi.FloatVal}
+	return &kiwi.Pair{Key: key, Val: strconv.FormatFloat(val, 'e', -1, 64), Type: kiwi.FloatVal, Raw: val}
 }
 
 // Bool formats pair for bool value.
 // Note: type helpers are experimental part of API and may be removed.
 func Bool(key string, val bool) *kiwi.Pair {
 	if val {
-		return &kiwi.Pair{key, "true", nil, kiwi.BooleanVal}
+		return &kiwi.Pair{Key: key, Val: "true", Type: kiwi.BooleanVal, Raw: val}
 	}
-	return &kiwi.Pair{key, "false", nil, kiwi.BooleanVal}
+	return &kiwi.Pair{Key: key, Val: "false", Type: kiwi.BooleanVal, Raw: val}
 }
 
 // Time formats pair for time.Time value.
 // Note: type helpers are experimental part of API and may be removed.
 func Time(key string, val time.Time, layout string) *kiwi.Pair {
-	return &kiwi.Pair{key, val.Format(layout), nil, kiwi.TimeVal}
+	return &kiwi.Pair{Key: key, Val: val.Format(layout), Type: kiwi.TimeVal, Raw: val}
 }
@@ -0,0 +1,192 @@
+package kiwi
+
+// This file consists of the logging of the structs driven by the field tags.
+
+/* Copyright (c) 2016-2020, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व */
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// SecretMask replaces the values of the struct fields tagged as
+// secret.
+var SecretMask = "******"
+
+// structPlans caches the plans of the struct types.
+var structPlans sync.Map
+
+type (
+	structPlan struct {
+		fields []structField
+	}
+	structField struct {
+		index     int
+		name      string
+		omitempty bool
+		secret    bool
+		inline    bool
+		// nested is true for the struct fields (or the pointers
+		// to the structs) that expanded to the pairs too.
+		nested bool
+	}
+)
+
+// Struct converts the exported fields of the struct to the list of
+// the pairs. The keys are the names of the fields prefixed with the
+// prefix and the dot (the names used as is for the empty prefix).
+// The nested structs expanded to the pairs with the name of the field
+// as the additional prefix. The list could be passed to Log(), Add()
+// and With() instead of the key-value pair. The fields could be
+// tuned by the tags:
+//
+//	type User struct {
+//		Name     string `kiwi:"name"`
+//		Email    string `kiwi:",omitempty"`
+//		Password string `kiwi:",secret"`
+//		Address  `kiwi:",inline"`
+//		Internal int `kiwi:"-"`
+//	}
+//
+// omitempty skips the field with the zero value, secret replaces the
+// value with SecretMask, inline expands the nested struct without the
+// additional prefix. The field with "-" tag is skipped. The fields of
+// the embedded structs are inlined by default. The values that are
+// not structs returned as the single pair with the prefix as the key.
+func Struct(prefix string, v interface{}) []*Pair {
+	var val = reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr && !val.IsNil() {
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct || isTypedValue(val) {
+		if prefix == "" {
+			prefix = MessageKey
+		}
+		return []*Pair{toPair(prefix, v)}
+	}
+	return appendStruct(nil, prefix, val, 0)
+}
+
+func appendStruct(pairs []*Pair, prefix string, val reflect.Value, depth int) []*Pair {
+	for _, f := range planOf(val.Type()).fields {
+		var (
+			fv  = val.Field(f.index)
+			key = f.name
+		)
+		if prefix != "" {
+			key = prefix + "." + f.name
+		}
+		if f.omitempty && fv.IsZero() {
+			continue
+		}
+		if f.secret {
+			pairs = append(pairs, &Pair{Key: key, Val: SecretMask, Type: StringVal})
+			continue
+		}
+		// The converter could be registered after the plan
+		// made so it is checked for each value.
+		if f.nested && depth < MaxObjectDepth && converterOf(fv.Type()) == nil {
+			for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				if f.inline {
+					key = prefix
+				}
+				pairs = appendStruct(pairs, key, fv, depth+1)
+				continue
+			}
+		}
+		pairs = append(pairs, toPair(key, fv.Interface()))
+	}
+	return pairs
+}
+
+// planOf returns the cached plan of the struct type or makes the new
+// one.
+func planOf(t reflect.Type) *structPlan {
+	if plan, ok := structPlans.Load(t); ok {
+		return plan.(*structPlan)
+	}
+	var plan = new(structPlan)
+	for i := 0; i < t.NumField(); i++ {
+		var (
+			sf     = t.Field(i)
+			field  = structField{index: i, name: sf.Name, inline: sf.Anonymous}
+			tag, _ = sf.Tag.Lookup("kiwi")
+		)
+		if sf.PkgPath != "" || tag == "-" {
+			continue
+		}
+		opts := strings.Split(tag, ",")
+		if opts[0] != "" {
+			field.name = opts[0]
+			field.inline = false
+		}
+		for _, opt := range opts[1:] {
+			switch opt {
+			case "omitempty":
+				field.omitempty = true
+			case "secret":
+				field.secret = true
+			case "inline":
+				field.inline = true
+			}
+		}
+		ft := sf.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		field.nested = ft.Kind() == reflect.Struct && !isTypedType(ft)
+		plan.fields = append(plan.fields, field)
+	}
+	structPlans.Store(t, plan)
+	return plan
+}
+
+// isTypedValue reports whether the struct value has own
+// representation so it should not be expanded to the fields.
+func isTypedValue(v reflect.Value) bool {
+	return isTypedType(v.Type())
+}
+
+func isTypedType(t reflect.Type) bool {
+	if t == timeType || converterOf(t) != nil {
+		return true
+	}
+	for _, i := range []reflect.Type{marshalerType, errorType, valuerType, stringerType, textMarshalerType} {
+		if t.Implements(i) || reflect.PtrTo(t).Implements(i) {
+			return true
+		}
+	}
+	return false
+}
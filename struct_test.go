@@ -0,0 +1,122 @@
+package kiwi
+
+/*
+Copyright (c) 2016-2018, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व
+
+All tests consists of three parts:
+
+- arrange structures and initialize objects for use in tests
+- act on testing object
+- check and assert on results
+
+These parts separated by empty lines in each test function.
+*/
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type structAddress struct {
+	City string `kiwi:"city"`
+}
+
+type structUser struct {
+	Name     string `kiwi:"name"`
+	Email    string `kiwi:"email,omitempty"`
+	Password string `kiwi:"password,secret"`
+	Age      int
+	Score    float64
+	Created  time.Time
+	Home     structAddress  `kiwi:"home"`
+	Work     *structAddress `kiwi:",inline"`
+	Skipped  string         `kiwi:"-"`
+	internal int
+}
+
+// Test of the struct fields converted to the pairs accordingly with
+// their tags and types.
+func TestStruct_Tags(t *testing.T) {
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	user := structUser{Name: "alice", Password: "qwerty", Age: 30, Score: 0.5, Created: created,
+		Home: structAddress{"Paris"}, Work: &structAddress{"Berlin"}, Skipped: "x", internal: 1}
+
+	pairs := Struct("user", &user)
+
+	expected := []Pair{
+		{Key: "user.name", Val: "alice", Type: StringVal},
+		{Key: "user.password", Val: SecretMask, Type: StringVal},
+		{Key: "user.Age", Val: "30", Type: IntegerVal},
+		{Key: "user.Score", Val: toPair("", 0.5).Val, Type: FloatVal},
+		{Key: "user.Created", Val: created.Format(TimeLayout), Type: TimeVal},
+		{Key: "user.home.city", Val: "Paris", Type: StringVal},
+		{Key: "user.city", Val: "Berlin", Type: StringVal},
+	}
+	if len(pairs) != len(expected) {
+		t.Fatalf("expected %d pairs, got %d", len(expected), len(pairs))
+	}
+	for i, p := range pairs {
+		if p.Key != expected[i].Key || p.Val != expected[i].Val || p.Type != expected[i].Type {
+			t.Errorf("pair %d: expected %+v, got %+v", i, expected[i], *p)
+		}
+	}
+}
+
+// Test of the struct passed to Log(). The pairs should be output
+// without the prefix for the empty prefix.
+func TestStruct_Log_Logfmt(t *testing.T) {
+	output := bytes.NewBufferString("")
+	log := New()
+	out := SinkTo(output, AsLogfmt()).Start()
+
+	log.Log(Struct("", structAddress{"Rome"}), "k", 1)
+
+	out.Close()
+	if strings.TrimSpace(output.String()) != `city="Rome" k=1` {
+		println(output.String())
+		t.Fail()
+	}
+}
+
+// Test of the plan cache. The plan of the same type should be built
+// once.
+func TestStruct_PlanCached(t *testing.T) {
+	Struct("a", structAddress{"x"})
+
+	plan, ok := structPlans.Load(reflect.TypeOf(structAddress{}))
+
+	if !ok || plan.(*structPlan) != planOf(reflect.TypeOf(structAddress{})) {
+		t.Fail()
+	}
+}
@@ -0,0 +1,314 @@
+package kiwi
+
+// This file consists of the sink for syslog (RFC-5424 and RFC-3164).
+
+/* Copyright (c) 2016-2020, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व */
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Syslog facilities.
+const (
+	SyslogKern = iota
+	SyslogUser
+	SyslogMail
+	SyslogDaemon
+	SyslogAuth
+	SyslogSyslog
+	SyslogLpr
+	SyslogNews
+	SyslogUucp
+	SyslogCron
+	SyslogAuthPriv
+	SyslogFtp
+	SyslogLocal0 = iota + 4
+	SyslogLocal1
+	SyslogLocal2
+	SyslogLocal3
+	SyslogLocal4
+	SyslogLocal5
+	SyslogLocal6
+	SyslogLocal7
+)
+
+// SyslogSDID is the ID of structured data element used for the pairs
+// of the record in RFC-5424 messages.
+var SyslogSDID = "kiwi@32473"
+
+const syslogTimeLayout = "2006-01-02T15:04:05.000000Z07:00"
+
+// SyslogOptions defines the properties of syslog messages.
+type SyslogOptions struct {
+	// Facility of the messages. Zero value means SyslogUser
+	// facility so use SyslogKern explicitly is not possible.
+	Facility int
+	// AppName by default is the name of the executable.
+	AppName string
+	// Hostname by default is the name returned by os.Hostname().
+	Hostname string
+	// RFC3164 selects legacy BSD syslog format instead of RFC-5424.
+	// The pairs of the record appended to the message in logfmt.
+	RFC3164 bool
+}
+
+// SinkToSyslog creates a new sink that writes the records to syslog
+// server. The network could be "udp", "tcp", "unix" or "unixgram". For
+// the stream connections (tcp, unix) the messages are framed with
+// octet counting (RFC-6587). For "unix" network the datagram socket
+// tried first as it is common for local syslog daemons. The
+// connection is reestablished if the write fails. The value of
+// LevelKey mapped to the syslog severity, MessageKey becomes the
+// message and other pairs passed as the structured data.
+//
+// As for SinkTo() the sink should be started explicitly. The
+// connection is closed when the sink closed.
+func SinkToSyslog(network, addr string, opts SyslogOptions) (*Sink, error) {
+	var w = &syslogWriter{network: network, addr: addr}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	var sink = SinkTo(w, newSyslogFormat(opts))
+	sink.closer = w
+	return sink, nil
+}
+
+type formatSyslog struct {
+	line     *bytes.Buffer
+	data     *bytes.Buffer
+	rest     *formatLogfmt
+	facility int
+	appName  string
+	hostname string
+	pid      string
+	rfc3164  bool
+	message  string
+	severity int
+}
+
+func newSyslogFormat(opts SyslogOptions) *formatSyslog {
+	var f = &formatSyslog{
+		line:     bytes.NewBuffer(make([]byte, 512)),
+		data:     bytes.NewBuffer(make([]byte, 512)),
+		rest:     AsLogfmt(),
+		facility: opts.Facility,
+		appName:  opts.AppName,
+		hostname: opts.Hostname,
+		pid:      strconv.Itoa(os.Getpid()),
+		rfc3164:  opts.RFC3164,
+	}
+	if f.facility == SyslogKern {
+		f.facility = SyslogUser
+	}
+	if f.appName == "" {
+		f.appName = filepath.Base(os.Args[0])
+	}
+	if f.hostname == "" {
+		f.hostname, _ = os.Hostname()
+	}
+	if f.hostname == "" {
+		f.hostname = "-"
+	}
+	return f
+}
+
+func (f *formatSyslog) Begin() {
+	f.line.Reset()
+	f.data.Reset()
+	f.rest.Begin()
+	f.message = ""
+	f.severity = sevInfo
+}
+
+func (f *formatSyslog) Pair(key, val string, valType int) {
+	switch key {
+	case MessageKey:
+		f.message = val
+		return
+	case LevelKey:
+		f.severity = syslogSeverity(val)
+		return
+	}
+	if f.rfc3164 {
+		f.rest.Pair(key, val, valType)
+		return
+	}
+	f.data.WriteRune(' ')
+	for i := 0; i < len(key) && i < 32; i++ {
+		c := key[i]
+		if c <= ' ' || c >= 127 || c == '=' || c == ']' || c == '"' {
+			c = '_'
+		}
+		f.data.WriteByte(c)
+	}
+	f.data.WriteString(`="`)
+	for _, r := range val {
+		if r == '"' || r == '\\' || r == ']' {
+			f.data.WriteRune('\\')
+		}
+		f.data.WriteRune(r)
+	}
+	f.data.WriteRune('"')
+}
+
+func (f *formatSyslog) Finish() []byte {
+	var now = time.Now()
+	f.line.WriteRune('<')
+	f.line.WriteString(strconv.Itoa(f.facility*8 + f.severity))
+	f.line.WriteRune('>')
+	if f.rfc3164 {
+		f.line.WriteString(now.Format(time.Stamp))
+		f.line.WriteRune(' ')
+		f.line.WriteString(f.hostname)
+		f.line.WriteRune(' ')
+		f.line.WriteString(f.appName)
+		f.line.WriteRune('[')
+		f.line.WriteString(f.pid)
+		f.line.WriteString("]: ")
+		f.line.WriteString(f.message)
+		if rest := bytes.TrimRight(f.rest.line.Bytes(), " "); len(rest) > 0 {
+			if f.message != "" {
+				f.line.WriteRune(' ')
+			}
+			f.line.Write(rest)
+		}
+		return f.line.Bytes()
+	}
+	f.line.WriteString("1 ")
+	f.line.WriteString(now.Format(syslogTimeLayout))
+	f.line.WriteRune(' ')
+	f.line.WriteString(f.hostname)
+	f.line.WriteRune(' ')
+	f.line.WriteString(f.appName)
+	f.line.WriteRune(' ')
+	f.line.WriteString(f.pid)
+	f.line.WriteString(" - ")
+	if f.data.Len() > 0 {
+		f.line.WriteRune('[')
+		f.line.WriteString(SyslogSDID)
+		f.line.Write(f.data.Bytes())
+		f.line.WriteRune(']')
+	} else {
+		f.line.WriteRune('-')
+	}
+	if f.message != "" {
+		f.line.WriteRune(' ')
+		f.line.WriteString(f.message)
+	}
+	return f.line.Bytes()
+}
+
+// syslogWriter sends the messages to syslog server and reconnects if
+// the connection is broken.
+type syslogWriter struct {
+	sync.Mutex
+	network string
+	addr    string
+	stream  bool
+	conn    net.Conn
+	frame   []byte
+}
+
+func (w *syslogWriter) connect() error {
+	var (
+		conn net.Conn
+		err  error
+	)
+	switch w.network {
+	case "unix":
+		if conn, err = net.Dial("unixgram", w.addr); err == nil {
+			w.stream = false
+			break
+		}
+		conn, err = net.Dial("unix", w.addr)
+		w.stream = true
+	default:
+		conn, err = net.Dial(w.network, w.addr)
+		w.stream = strings.HasPrefix(w.network, "tcp")
+	}
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	return nil
+}
+
+// Write sends the single message. If the write fails the writer
+// reconnects and tries to send the message once again.
+func (w *syslogWriter) Write(p []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if w.conn == nil {
+			if err = w.connect(); err != nil {
+				continue
+			}
+		}
+		if err = w.send(p); err == nil {
+			return len(p), nil
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+	return 0, err
+}
+
+func (w *syslogWriter) send(p []byte) error {
+	if !w.stream {
+		_, err := w.conn.Write(p)
+		return err
+	}
+	w.frame = strconv.AppendInt(w.frame[:0], int64(len(p)), 10)
+	w.frame = append(w.frame, ' ')
+	w.frame = append(w.frame, p...)
+	_, err := w.conn.Write(w.frame)
+	return err
+}
+
+// Close closes the connection to syslog server.
+func (w *syslogWriter) Close() error {
+	w.Lock()
+	defer w.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
@@ -0,0 +1,161 @@
+package kiwi
+
+/*
+Copyright (c) 2016-2018, Alexander I.Grafov <grafov@gmail.com>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+* Neither the name of kvlog nor the names of its
+  contributors may be used to endorse or promote products derived from
+  this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+ॐ तारे तुत्तारे तुरे स्व
+
+All tests consists of three parts:
+
+- arrange structures and initialize objects for use in tests
+- act on testing object
+- check and assert on results
+
+These parts separated by empty lines in each test function.
+*/
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test of syslog sink over UDP. The message should be formatted in
+// accordance with RFC-5424.
+func TestSyslog_UDP(t *testing.T) {
+	l, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	log := New()
+	out, err := SinkToSyslog("udp", l.LocalAddr().String(), SyslogOptions{Facility: SyslogLocal0, AppName: "test", Hostname: "host"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.Start()
+
+	log.Log(LevelKey, "error", MessageKey, "hello", "key", `a "quoted" [value]`)
+
+	out.Close()
+	buf := make([]byte, 1024)
+	l.SetReadDeadline(time.Now().Add(3 * time.Second))
+	n, _, err := l.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := regexp.MustCompile(`^<131>1 \S+ host test ` + strconv.Itoa(os.Getpid()) +
+		regexp.QuoteMeta(` - [kiwi@32473 key="a \"quoted\" [value\]"] hello`) + `$`)
+	if !expected.Match(buf[:n]) {
+		t.Errorf("unexpected message %q", buf[:n])
+	}
+}
+
+// Test of syslog sink in RFC-3164 format.
+func TestSyslog_RFC3164(t *testing.T) {
+	f := newSyslogFormat(SyslogOptions{AppName: "test", Hostname: "host", RFC3164: true})
+
+	f.Begin()
+	f.Pair(MessageKey, "hello", StringVal)
+	f.Pair(LevelKey, "debug", StringVal)
+	f.Pair("n", "1", IntegerVal)
+	result := string(f.Finish())
+
+	expected := regexp.MustCompile(`^<15>\w{3} [ \d]\d \d\d:\d\d:\d\d host test\[\d+\]: hello n=1$`)
+	if !expected.MatchString(result) {
+		t.Errorf("unexpected message %q", result)
+	}
+}
+
+// Test of octet counting framing for TCP and reconnection after the
+// connection was closed by the server.
+func TestSyslog_TCPReconnect(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	w := &syslogWriter{network: "tcp", addr: l.Addr().String()}
+	if err = w.connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w.Write([]byte("<14>first"))
+	r := bufio.NewReader(conn)
+	if msg := readSyslogFrame(t, r); msg != "<14>first" {
+		t.Errorf("unexpected message %q", msg)
+	}
+	conn.Close()
+	accepted := make(chan net.Conn)
+	go func() {
+		c, _ := l.Accept()
+		accepted <- c
+	}()
+	var second net.Conn
+	for second == nil {
+		w.Write([]byte("<14>second"))
+		select {
+		case second = <-accepted:
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	defer second.Close()
+
+	second.SetReadDeadline(time.Now().Add(3 * time.Second))
+	if msg := readSyslogFrame(t, bufio.NewReader(second)); msg != "<14>second" {
+		t.Errorf("unexpected message %q", msg)
+	}
+}
+
+func readSyslogFrame(t *testing.T, r *bufio.Reader) string {
+	size, err := r.ReadString(' ')
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(size))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := make([]byte, n)
+	if _, err = io.ReadFull(r, msg); err != nil {
+		t.Fatal(err)
+	}
+	return string(msg)
+}
@@ -54,21 +54,12 @@ const (
 //
 // log.Add(where.What(where.Filename, where.Func, where.Line)...)
 func What(parts int) []*kiwi.Pair {
-	var (
-		pairs []*kiwi.Pair
-		skip  = stackJump
-	)
+	var pairs []*kiwi.Pair
 	if parts&FilePos > 0 {
 		pairs = []*kiwi.Pair{{
 			Key: "file",
 			Eval: func() string {
-			start:
-				pc, file, line, _ := runtime.Caller(skip)
-				function := runtime.FuncForPC(pc).Name()
-				if strings.LastIndex(function, "grafov/kiwi.") != -1 {
-					skip++
-					goto start
-				}
+				_, file, line := caller()
 				return file + ":" + strconv.Itoa(line)
 			},
 			Type: kiwi.StringVal}}
@@ -77,11 +68,27 @@ func What(parts int) []*kiwi.Pair {
 		pairs = append(pairs, &kiwi.Pair{
 			Key: "func",
 			Eval: func() string {
-				pc, _, _, _ := runtime.Caller(skip)
-				return runtime.FuncForPC(pc).Name()
+				function, _, _ := caller()
+				return function
 			},
 			Type: kiwi.StringVal,
 		})
 	}
 	return pairs
 }
+
+// caller returns the function, the file and the line of the code
+// that called the logger. The frames of the kiwi package are skipped
+// because the delayed values evaluated by it.
+func caller() (function, file string, line int) {
+	for skip := stackJump; ; skip++ {
+		pc, f, l, ok := runtime.Caller(skip)
+		if !ok {
+			return "", "", 0
+		}
+		function = runtime.FuncForPC(pc).Name()
+		if strings.LastIndex(function, "grafov/kiwi.") == -1 {
+			return function, f, l
+		}
+	}
+}
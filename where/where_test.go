@@ -57,12 +57,12 @@ func TestWhere_GetAllInfo_Logfmt(t *testing.T) {
 	log.Log("key", "value")
 
 	out.Close()
-	expected := `file="`
+	expected := `where_test.go:`
 	if !strings.Contains(stream.String(), expected) {
 		t.Logf("expected %s got %v", expected, stream.String())
 		t.Fail()
 	}
-	expected = `func="`
+	expected = `func="github.com/grafov/kiwi/where.TestWhere_GetAllInfo_Logfmt"`
 	if !strings.Contains(stream.String(), expected) {
 		t.Logf("expected %s got %v", expected, stream.String())
 		t.Fail()
@@ -83,7 +83,7 @@ func TestWhereGlobal_GetAllInfo_Logfmt(t *testing.T) {
 		t.Logf("expected '%s' in '%v'", expected, stream.String())
 		t.Fail()
 	}
-	expected = `func="`
+	expected = `func="github.com/grafov/kiwi/where.TestWhereGlobal_GetAllInfo_Logfmt"`
 	if !strings.Contains(stream.String(), expected) {
 		t.Logf("expected '%s' in '%v'", expected, stream.String())
 		t.Fail()